@@ -2,64 +2,236 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/config"
 	"github.com/itk/wallet/internal/handlers"
+	"github.com/itk/wallet/internal/logging"
+	"github.com/itk/wallet/internal/metrics"
+	"github.com/itk/wallet/internal/middleware"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/outbox"
+	"github.com/itk/wallet/internal/pkg/migrate"
 	"github.com/itk/wallet/internal/pkg/postgres"
+	"github.com/itk/wallet/internal/ratelimit"
 	"github.com/itk/wallet/internal/repository"
 	"github.com/itk/wallet/internal/service"
-	"github.com/joho/godotenv"
+	"github.com/itk/wallet/internal/tracing"
+	"github.com/itk/wallet/internal/webhook"
+	"github.com/itk/wallet/migrations"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
-	if err := godotenv.Load("config.env"); err != nil {
-		log.Printf("Failed to load config.env: %v", err)
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		log.Fatal("DATABASE_URL is not set")
+	logger := logging.New(os.Stdout, cfg.LogLevel)
+	slog.SetDefault(logger)
+	gin.SetMode(cfg.Server.GinMode)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal(logger, "failed to set up tracing", err)
 	}
 
-	db, err := postgres.NewPostgresDB(databaseURL)
+	db, err := postgres.NewPostgresDB(cfg.Database.URL, postgres.PoolConfig{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal(logger, "failed to connect to database", err)
 	}
 	defer db.Close()
-	log.Println("Database connected successfully")
+	logger.Info("database connected")
 
-	walletRepo := repository.NewWalletRepository(db)
-	walletService := service.NewWalletService(walletRepo)
-	walletHandler := handlers.NewWalletHandler(walletService)
+	migrator, err := migrate.NewMigrator(db, migrations.FS)
+	if err != nil {
+		fatal(logger, "failed to load migrations", err)
+	}
 
-	router := gin.Default()
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+	walletRepo := repository.NewWalletRepository(db, logger)
+	if cfg.Outbox.Sink == outbox.SinkNone && !cfg.Webhooks.Enabled {
+		// Nothing would ever relay or delete the events.
+		walletRepo.DisableEvents()
+	}
+	walletService := service.NewWalletService(walletRepo, logger)
 
-	v1 := router.Group("/api/v1")
-	{
-		v1.POST("/wallet", walletHandler.ProcessOperation)
-		v1.GET("/wallets/:WALLET_UUID", walletHandler.GetBalance)
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			err = runMigrate(context.Background(), migrator, args[1:])
+		case "apikey":
+			err = runAPIKey(context.Background(), apiKeyService, args[1:])
+		case "reconcile":
+			err = runReconcile(context.Background(), walletService, cfg.Reconcile.ReportDir, args[1:])
+		default:
+			err = fmt.Errorf("unknown command %q", args[0])
+		}
+		if err != nil {
+			fatal(logger, "command failed", err)
+		}
+		return
+	}
+
+	if cfg.MigrateOnStart {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			fatal(logger, "failed to apply migrations", err)
+		}
+		logger.Info("migrations applied", slog.Any("versions", applied))
+	}
+
+	walletHandler := handlers.NewWalletHandler(walletService, cfg.AutoCreateWallets, logger)
+	idempotencyRepo := repository.NewIdempotencyRepository(db, cfg.Idempotency.Lease)
+	healthHandler := handlers.NewHealthHandler(db, migrator, cfg.Server.HealthTimeout)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, logger)
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookHandler := handlers.NewWebhookHandler(service.NewWebhookService(webhookRepo, cfg.Webhooks.AllowPrivateTargets), logger)
+
+	authenticate := middleware.AllowAnonymous()
+	if cfg.Auth.Enabled {
+		var tokens middleware.TokenVerifier
+		if cfg.Auth.JWT.Enabled() {
+			verifier, err := auth.NewJWTVerifier(context.Background(), cfg.Auth.JWT)
+			if err != nil {
+				fatal(logger, "failed to set up JWT verification", err)
+			}
+			tokens = verifier
+		}
+		authenticate = middleware.Authenticate(apiKeyService, tokens, logger)
+	}
+
+	var rateLimitStore ratelimit.Store
+	var rateLimitRepo *repository.RateLimitRepository
+	switch cfg.RateLimit.Backend {
+	case ratelimit.BackendMemory:
+		rateLimitStore = ratelimit.NewMemoryStore()
+	case ratelimit.BackendPostgres:
+		rateLimitRepo = repository.NewRateLimitRepository(db)
+		rateLimitStore = rateLimitRepo
 	}
 
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080"
+	metrics.RegisterDB(db, "wallet")
+
+	router := gin.New()
+	router.Use(gin.Recovery(), middleware.RequestID(), otelgin.Middleware(tracing.ServiceName),
+		middleware.AccessLog(logger), metrics.Middleware())
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+
+	v1 := router.Group("/api/v1", authenticate)
+	if rateLimitStore != nil {
+		v1.Use(middleware.RateLimit(rateLimitStore, cfg.RateLimit, logger))
+	}
+	{
+		v1.POST("/wallet", middleware.Idempotency(idempotencyRepo, logger), walletHandler.ProcessOperation)
+		v1.POST("/wallet/batch", middleware.Idempotency(idempotencyRepo, logger), walletHandler.ProcessBatch)
+		v1.POST("/wallets", middleware.Idempotency(idempotencyRepo, logger), walletHandler.CreateWallet)
+		v1.GET("/wallets/:WALLET_UUID", walletHandler.GetWallet)
+		v1.GET("/wallets/:WALLET_UUID/balance", walletHandler.GetBalance)
+		v1.GET("/wallets/:WALLET_UUID/operations", walletHandler.ListOperations)
+		v1.POST("/operations/:OPERATION_ID/reverse", middleware.RequireRole(models.RoleAdmin), middleware.Idempotency(idempotencyRepo, logger), walletHandler.ReverseOperation)
+		v1.POST("/transfers", middleware.Idempotency(idempotencyRepo, logger), walletHandler.Transfer)
+		v1.POST("/wallets/:WALLET_UUID/holds", middleware.Idempotency(idempotencyRepo, logger), walletHandler.CreateHold)
+		v1.GET("/holds/:HOLD_ID", walletHandler.GetHold)
+		v1.POST("/holds/:HOLD_ID/capture", middleware.Idempotency(idempotencyRepo, logger), walletHandler.CaptureHold)
+		v1.POST("/holds/:HOLD_ID/release", middleware.Idempotency(idempotencyRepo, logger), walletHandler.ReleaseHold)
+
+		// Without the dispatcher subscriptions would never receive anything.
+		if cfg.Webhooks.Enabled {
+			v1.POST("/webhooks", webhookHandler.CreateSubscription)
+			v1.GET("/webhooks", webhookHandler.ListSubscriptions)
+			v1.GET("/webhooks/:WEBHOOK_ID", webhookHandler.GetSubscription)
+			v1.PUT("/webhooks/:WEBHOOK_ID", webhookHandler.UpdateSubscription)
+			v1.DELETE("/webhooks/:WEBHOOK_ID", webhookHandler.DeleteSubscription)
+			v1.POST("/webhooks/:WEBHOOK_ID/rotate-secret", webhookHandler.RotateSecret)
+			v1.GET("/webhooks/:WEBHOOK_ID/deliveries", webhookHandler.ListDeliveries)
+			v1.POST("/webhooks/:WEBHOOK_ID/deliveries/:DELIVERY_ID/retry", webhookHandler.RetryDelivery)
+		}
+
+		admin := v1.Group("/admin", middleware.RequireRole(models.RoleAdmin))
+		admin.GET("/limits", walletHandler.GetDefaultLimits)
+		admin.PUT("/limits", walletHandler.SetDefaultLimits)
+		admin.DELETE("/limits", walletHandler.DeleteDefaultLimits)
+		admin.GET("/wallets/:WALLET_UUID/limits", walletHandler.GetWalletLimits)
+		admin.PUT("/wallets/:WALLET_UUID/limits", walletHandler.SetWalletLimits)
+		admin.DELETE("/wallets/:WALLET_UUID/limits", walletHandler.DeleteWalletLimits)
+		admin.PUT("/wallets/:WALLET_UUID/status", walletHandler.SetWalletStatus)
+		admin.GET("/wallets/:WALLET_UUID/status-history", walletHandler.ListStatusChanges)
+		admin.POST("/wallets/:WALLET_UUID/owners", walletHandler.AddWalletOwner)
+		admin.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+		admin.DELETE("/api-keys/:KEY_ID", apiKeyHandler.RevokeAPIKey)
 	}
 
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go expireHolds(workersCtx, walletService, logger, cfg.HoldExpiryInterval)
+	go sweepIdempotencyKeys(workersCtx, idempotencyRepo, logger, cfg.Idempotency)
+	if rateLimitRepo != nil && cfg.RateLimit.RefillTime() > 0 {
+		go sweepRateLimitBuckets(workersCtx, rateLimitRepo, logger, cfg.RateLimit.RefillTime())
+	}
+	if cfg.Reconcile.Interval > 0 {
+		go reconcileBalances(workersCtx, walletService, logger, cfg.Reconcile)
+	}
+	if cfg.Snapshots.Interval > 0 {
+		go snapshotBalances(workersCtx, walletService, logger, cfg.Snapshots)
+	}
+
+	var sinks outbox.MultiSink
+	sink, err := outbox.NewSink(cfg.Outbox)
+	if err != nil {
+		fatal(logger, "failed to set up outbox sink", err)
+	}
+	if sink != nil {
+		sinks = append(sinks, sink)
+	}
+
+	var relays sync.WaitGroup
+	if cfg.Webhooks.Enabled {
+		sinks = append(sinks, webhook.NewFanout(webhookRepo))
+		dispatcher := webhook.NewDispatcher(webhookRepo, cfg.Webhooks, logger)
+		relays.Go(func() { dispatcher.Run(workersCtx) })
+	}
+	if len(sinks) > 0 {
+		outboxRepo := repository.NewOutboxRepository(db)
+		relay := outbox.NewRelay(outboxRepo, sinks, cfg.Outbox, logger)
+		relays.Go(func() { relay.Run(workersCtx) })
+		go sweepOutboxEvents(workersCtx, outboxRepo, logger, cfg.Outbox.Retention)
 	}
 
 	go func() {
-		log.Printf("Server starting on port %s", port)
+		logger.Info("server starting", slog.Int("port", cfg.Server.Port))
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal(logger, "failed to start server", err)
 		}
 	}()
 
@@ -67,12 +239,295 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Server shuts down")
+	logger.Info("server shutting down")
+	healthHandler.SetDraining()
+	time.Sleep(cfg.Server.DrainDelay)
+	stopWorkers()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal(logger, "server forced to shutdown", err)
+	}
+
+	relays.Wait()
+	if err := sinks.Close(); err != nil {
+		logger.Error("failed to close outbox sinks", slog.Any("error", err))
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("failed to flush traces", slog.Any("error", err))
+	}
+}
+
+func expireHolds(ctx context.Context, walletService *service.WalletService, logger *slog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := walletService.ExpireHolds(ctx)
+			if err != nil {
+				logger.Error("failed to expire holds", slog.Any("error", err))
+				continue
+			}
+			if expired > 0 {
+				logger.Info("holds expired", slog.Int64("count", expired))
+			}
+		}
+	}
+}
+
+func sweepIdempotencyKeys(ctx context.Context, idempotencyRepo *repository.IdempotencyRepository, logger *slog.Logger, cfg config.Idempotency) {
+	ticker := time.NewTicker(cfg.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := idempotencyRepo.DeleteExpired(ctx, time.Now().Add(-cfg.Retention))
+			if err != nil {
+				logger.Error("failed to delete expired idempotency keys", slog.Any("error", err))
+				continue
+			}
+			if deleted > 0 {
+				logger.Info("expired idempotency keys deleted", slog.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+// sweepOutboxEvents deletes events published more than retention ago.
+func sweepOutboxEvents(ctx context.Context, outboxRepo *repository.OutboxRepository, logger *slog.Logger, retention time.Duration) {
+	ticker := time.NewTicker(min(retention, time.Hour))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := outboxRepo.DeletePublished(ctx, time.Now().Add(-retention))
+			if err != nil {
+				logger.Error("failed to delete published outbox events", slog.Any("error", err))
+				continue
+			}
+			if deleted > 0 {
+				logger.Info("published outbox events deleted", slog.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+// sweepRateLimitBuckets deletes buckets idle for longer than refill, which
+// have refilled completely and would be recreated as full anyway.
+func sweepRateLimitBuckets(ctx context.Context, rateLimitRepo *repository.RateLimitRepository, logger *slog.Logger, refill time.Duration) {
+	ticker := time.NewTicker(max(refill, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := rateLimitRepo.DeleteIdle(ctx, refill)
+			if err != nil {
+				logger.Error("failed to delete idle rate limit buckets", slog.Any("error", err))
+				continue
+			}
+			if deleted > 0 {
+				logger.Debug("idle rate limit buckets deleted", slog.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+func snapshotBalances(ctx context.Context, walletService *service.WalletService, logger *slog.Logger, cfg config.Snapshots) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			taken, err := walletService.SnapshotBalances(ctx, cfg.Every)
+			if err != nil {
+				logger.Error("failed to snapshot balances", slog.Any("error", err))
+				continue
+			}
+			if taken > 0 {
+				logger.Info("balance snapshots taken", slog.Int("count", taken))
+			}
+		}
+	}
+}
+
+// reconcileBalances runs the scheduled reconciliation. Every replica runs the
+// job, but only the one holding the reconciliation lock does the work.
+func reconcileBalances(ctx context.Context, walletService *service.WalletService, logger *slog.Logger, cfg config.Reconcile) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := walletService.Reconcile(ctx, cfg.Repair, "reconciliation-job")
+			if errors.Is(err, models.ErrReconcileRunning) {
+				continue
+			}
+			if err != nil {
+				logger.Error("failed to reconcile balances", slog.Any("error", err))
+				continue
+			}
+			if cfg.ReportDir != "" {
+				if _, err := writeReport(cfg.ReportDir, report); err != nil {
+					logger.Error("failed to write reconciliation report", slog.Any("error", err))
+				}
+			}
+		}
+	}
+}
+
+func runMigrate(ctx context.Context, migrator *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [N] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied migrations: %v\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted migrations: %v\n", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	return nil
+}
+
+// runAPIKey issues API keys from the command line, e.g. the first admin key:
+// apikey create <name> <principal> [CLIENT|ADMIN]
+func runAPIKey(ctx context.Context, apiKeyService *service.APIKeyService, args []string) error {
+	if len(args) < 3 || args[0] != "create" {
+		return fmt.Errorf("usage: apikey create <name> <principal> [CLIENT|ADMIN]")
+	}
+
+	role := models.RoleClient
+	if len(args) > 3 {
+		role = models.Role(args[3])
+	}
+
+	key, err := apiKeyService.CreateAPIKey(ctx, args[1], args[2], role)
+	if err != nil {
+		return err
 	}
+	fmt.Printf("id: %s\nkey: %s\n", key.ID, key.Key)
+
+	return nil
+}
+
+// runReconcile checks stored balances against the ledger and prints the
+// report, optionally repairing them on behalf of actor:
+// reconcile check | repair <actor>
+// It fails when mismatches remain, so a scheduler can alert on the exit code.
+func runReconcile(ctx context.Context, walletService *service.WalletService, reportDir string, args []string) error {
+	usage := fmt.Errorf("usage: reconcile check | repair <actor>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	var repair bool
+	var actor string
+	switch {
+	case args[0] == "check" && len(args) == 1:
+	case args[0] == "repair" && len(args) == 2:
+		repair, actor = true, args[1]
+	default:
+		return usage
+	}
+
+	report, err := walletService.Reconcile(ctx, repair, actor)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(body))
+
+	if reportDir != "" {
+		path, err := writeReport(reportDir, report)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Report written to %s\n", path)
+	}
+
+	if unresolved := len(report.Mismatches) - report.Repaired; unresolved > 0 {
+		return fmt.Errorf("%d balances differ from the ledger", unresolved)
+	}
+
+	return nil
+}
+
+func writeReport(dir string, report *models.ReconciliationReport) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "reconciliation-"+report.StartedAt.Format("20060102T150405Z")+".json")
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return path, nil
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
 }
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+type JWTConfig struct {
+	JWKSURL  string
+	JWKSFile string
+	Issuer   string
+	Audience string
+}
+
+func (c JWTConfig) Enabled() bool {
+	return c.JWKSURL != "" || c.JWKSFile != ""
+}
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Role    string   `json:"role"`
+	Wallets []string `json:"wallets"`
+}
+
+// JWTVerifier validates bearer tokens against a JWK set loaded from a file or
+// fetched, and periodically refreshed, from a URL.
+type JWTVerifier struct {
+	keys   keyfunc.Keyfunc
+	parser *jwt.Parser
+}
+
+func NewJWTVerifier(ctx context.Context, cfg JWTConfig) (*JWTVerifier, error) {
+	var keys keyfunc.Keyfunc
+	var err error
+	if cfg.JWKSFile != "" {
+		raw, readErr := os.ReadFile(cfg.JWKSFile)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", readErr)
+		}
+		keys, err = keyfunc.NewJWKSetJSON(json.RawMessage(raw))
+	} else {
+		keys, err = keyfunc.NewDefaultCtx(ctx, []string{cfg.JWKSURL})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"})}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWTVerifier{
+		keys:   keys,
+		parser: jwt.NewParser(opts...),
+	}, nil
+}
+
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*models.Principal, error) {
+	var claims Claims
+	if _, err := v.parser.ParseWithClaims(token, &claims, v.keys.KeyfuncCtx(ctx)); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrUnauthenticated, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", models.ErrUnauthenticated)
+	}
+
+	principal := &models.Principal{ID: claims.Subject, Role: models.RoleClient}
+	if strings.EqualFold(claims.Role, string(models.RoleAdmin)) {
+		principal.Role = models.RoleAdmin
+	}
+	for _, wallet := range claims.Wallets {
+		walletID, err := uuid.Parse(wallet)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid wallet claim %q", models.ErrUnauthenticated, wallet)
+		}
+		principal.WalletIDs = append(principal.WalletIDs, walletID)
+	}
+
+	return principal, nil
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+func newTestVerifier(t *testing.T) (*JWTVerifier, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "test-key",
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+
+	verifier, err := NewJWTVerifier(context.Background(), JWTConfig{JWKSFile: path, Issuer: "https://auth.itk"})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	return verifier, key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	verifier, key := newTestVerifier(t)
+	walletID := uuid.New()
+	valid := jwt.RegisteredClaims{
+		Subject:   "merchant-1",
+		Issuer:    "https://auth.itk",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	principal, err := verifier.Verify(context.Background(), signToken(t, key, Claims{RegisteredClaims: valid, Wallets: []string{walletID.String()}}))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if principal.ID != "merchant-1" || principal.IsAdmin() || !principal.HasWalletGrant(walletID) {
+		t.Errorf("unexpected principal %+v", principal)
+	}
+
+	admin, err := verifier.Verify(context.Background(), signToken(t, key, Claims{RegisteredClaims: valid, Role: "admin"}))
+	if err != nil || !admin.IsAdmin() {
+		t.Errorf("expected admin principal, got %+v, %v", admin, err)
+	}
+
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "https://evil.example"
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	for name, token := range map[string]string{
+		"expired":      signToken(t, key, Claims{RegisteredClaims: expired}),
+		"wrong issuer": signToken(t, key, Claims{RegisteredClaims: wrongIssuer}),
+		"unknown key":  signToken(t, otherKey, Claims{RegisteredClaims: valid}),
+		"garbage":      "not-a-jwt",
+	} {
+		if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, models.ErrUnauthenticated) {
+			t.Errorf("%s: expected ErrUnauthenticated, got %v", name, err)
+		}
+	}
+}
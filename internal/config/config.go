@@ -0,0 +1,434 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/outbox"
+	"github.com/itk/wallet/internal/ratelimit"
+	"github.com/itk/wallet/internal/tracing"
+	"github.com/itk/wallet/internal/webhook"
+	"github.com/joho/godotenv"
+)
+
+const DefaultFile = "config.env"
+
+type Config struct {
+	Server             Server
+	Database           Database
+	Tracing            tracing.Config
+	Auth               Auth
+	RateLimit          ratelimit.Config
+	Outbox             outbox.Config
+	Webhooks           webhook.Config
+	Reconcile          Reconcile
+	Snapshots          Snapshots
+	Idempotency        Idempotency
+	LogLevel           slog.Level
+	AutoCreateWallets  bool
+	MigrateOnStart     bool
+	HoldExpiryInterval time.Duration
+}
+
+type Server struct {
+	Port            int
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration
+	HealthTimeout   time.Duration
+	GinMode         string
+}
+
+type Auth struct {
+	Enabled bool
+	JWT     auth.JWTConfig
+}
+
+// Reconcile schedules the job that checks stored balances against the ledger.
+// A zero Interval disables it; the reconcile command still works.
+type Reconcile struct {
+	Interval  time.Duration
+	Repair    bool
+	ReportDir string
+}
+
+// Snapshots schedules the job that records ledger balances, so point-in-time
+// queries sum at most about Every operations. A zero Interval disables it.
+type Snapshots struct {
+	Interval time.Duration
+	Every    int
+}
+
+// Idempotency bounds how long keys live. A reservation that neither took
+// effect nor completed within Lease may be taken over by a retry; keys are
+// deleted after Retention by a sweep every SweepInterval.
+type Idempotency struct {
+	Lease         time.Duration
+	Retention     time.Duration
+	SweepInterval time.Duration
+}
+
+type Database struct {
+	URL             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:            8080,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 5 * time.Second,
+			HealthTimeout:   2 * time.Second,
+			GinMode:         gin.DebugMode,
+		},
+		Database: Database{
+			MaxOpenConns: 25,
+			MaxIdleConns: 5,
+		},
+		Tracing: tracing.Config{
+			Exporter:    tracing.ExporterNone,
+			Endpoint:    "http://localhost:4318",
+			SampleRatio: 1,
+		},
+		RateLimit: ratelimit.Config{
+			Backend: ratelimit.BackendMemory,
+			Client:  ratelimit.Limit{Rate: 100, Burst: 200},
+			Wallet:  ratelimit.Limit{Rate: 50, Burst: 100},
+		},
+		Outbox: outbox.Config{
+			Sink:           outbox.SinkNone,
+			KafkaTopic:     "wallet-events",
+			PollInterval:   time.Second,
+			BatchSize:      100,
+			PublishTimeout: 5 * time.Second,
+			MaxBackoff:     5 * time.Minute,
+			Retention:      7 * 24 * time.Hour,
+		},
+		Webhooks: webhook.Config{
+			Enabled:      true,
+			PollInterval: time.Second,
+			BatchSize:    50,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  10,
+			MaxBackoff:   time.Hour,
+		},
+		Reconcile:          Reconcile{Interval: 24 * time.Hour},
+		Snapshots:          Snapshots{Interval: time.Hour, Every: 1000},
+		Idempotency:        Idempotency{Lease: time.Minute, Retention: 24 * time.Hour, SweepInterval: time.Hour},
+		Auth:               Auth{Enabled: true},
+		LogLevel:           slog.LevelInfo,
+		AutoCreateWallets:  true,
+		HoldExpiryInterval: time.Minute,
+	}
+}
+
+// Load builds the configuration from defaults, then the env file named by
+// CONFIG_FILE (config.env by default), then the process environment, then
+// command-line flags, each overriding the previous one. It returns the
+// arguments left after the flags, which select a subcommand.
+func Load(args []string) (*Config, []string, error) {
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = DefaultFile
+	}
+
+	file, err := godotenv.Read(path)
+	if err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		file = map[string]string{}
+	}
+
+	lookup := func(key string) (string, bool) {
+		if value, ok := os.LookupEnv(key); ok {
+			return value, true
+		}
+		value, ok := file[key]
+		return value, ok
+	}
+
+	cfg := Default()
+	if err := cfg.fromEnv(lookup); err != nil {
+		return nil, nil, err
+	}
+
+	flags := cfg.flagSet()
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return &cfg, flags.Args(), nil
+}
+
+func (c *Config) fromEnv(lookup func(string) (string, bool)) error {
+	l := loader{lookup: lookup}
+	l.int(&c.Server.Port, "SERVER_PORT")
+	l.duration(&c.Server.ReadTimeout, "HTTP_READ_TIMEOUT")
+	l.duration(&c.Server.WriteTimeout, "HTTP_WRITE_TIMEOUT")
+	l.duration(&c.Server.IdleTimeout, "HTTP_IDLE_TIMEOUT")
+	l.duration(&c.Server.ShutdownTimeout, "SHUTDOWN_TIMEOUT")
+	l.duration(&c.Server.DrainDelay, "SHUTDOWN_DRAIN_DELAY")
+	l.duration(&c.Server.HealthTimeout, "HEALTH_CHECK_TIMEOUT")
+	l.string(&c.Server.GinMode, "GIN_MODE")
+	l.string(&c.Database.URL, "DATABASE_URL")
+	l.int(&c.Database.MaxOpenConns, "DB_MAX_OPEN_CONNS")
+	l.int(&c.Database.MaxIdleConns, "DB_MAX_IDLE_CONNS")
+	l.duration(&c.Database.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME")
+	l.duration(&c.Database.ConnMaxIdleTime, "DB_CONN_MAX_IDLE_TIME")
+	l.string(&c.Tracing.Exporter, "TRACING_EXPORTER")
+	l.string(&c.Tracing.Endpoint, "TRACING_OTLP_ENDPOINT")
+	l.bool(&c.Tracing.Insecure, "TRACING_OTLP_INSECURE")
+	l.string(&c.Tracing.File, "TRACING_FILE")
+	l.float(&c.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO")
+	l.bool(&c.Auth.Enabled, "AUTH_ENABLED")
+	l.string(&c.Auth.JWT.JWKSURL, "AUTH_JWKS_URL")
+	l.string(&c.Auth.JWT.JWKSFile, "AUTH_JWKS_FILE")
+	l.string(&c.Auth.JWT.Issuer, "AUTH_JWT_ISSUER")
+	l.string(&c.Auth.JWT.Audience, "AUTH_JWT_AUDIENCE")
+	l.string(&c.RateLimit.Backend, "RATE_LIMIT_BACKEND")
+	l.float(&c.RateLimit.Client.Rate, "RATE_LIMIT_CLIENT_RPS")
+	l.int(&c.RateLimit.Client.Burst, "RATE_LIMIT_CLIENT_BURST")
+	l.float(&c.RateLimit.Wallet.Rate, "RATE_LIMIT_WALLET_RPS")
+	l.int(&c.RateLimit.Wallet.Burst, "RATE_LIMIT_WALLET_BURST")
+	l.string(&c.Outbox.Sink, "OUTBOX_SINK")
+	l.string(&c.Outbox.WebhookURL, "OUTBOX_WEBHOOK_URL")
+	l.string(&c.Outbox.KafkaBrokers, "OUTBOX_KAFKA_BROKERS")
+	l.string(&c.Outbox.KafkaTopic, "OUTBOX_KAFKA_TOPIC")
+	l.string(&c.Outbox.File, "OUTBOX_FILE")
+	l.duration(&c.Outbox.PollInterval, "OUTBOX_POLL_INTERVAL")
+	l.int(&c.Outbox.BatchSize, "OUTBOX_BATCH_SIZE")
+	l.duration(&c.Outbox.PublishTimeout, "OUTBOX_PUBLISH_TIMEOUT")
+	l.duration(&c.Outbox.MaxBackoff, "OUTBOX_MAX_BACKOFF")
+	l.duration(&c.Outbox.Retention, "OUTBOX_RETENTION")
+	l.bool(&c.Webhooks.Enabled, "WEBHOOKS_ENABLED")
+	l.duration(&c.Webhooks.PollInterval, "WEBHOOK_POLL_INTERVAL")
+	l.int(&c.Webhooks.BatchSize, "WEBHOOK_BATCH_SIZE")
+	l.duration(&c.Webhooks.Timeout, "WEBHOOK_TIMEOUT")
+	l.int(&c.Webhooks.MaxAttempts, "WEBHOOK_MAX_ATTEMPTS")
+	l.duration(&c.Webhooks.MaxBackoff, "WEBHOOK_MAX_BACKOFF")
+	l.bool(&c.Webhooks.AllowPrivateTargets, "WEBHOOK_ALLOW_PRIVATE_TARGETS")
+	l.duration(&c.Reconcile.Interval, "RECONCILE_INTERVAL")
+	l.bool(&c.Reconcile.Repair, "RECONCILE_REPAIR")
+	l.string(&c.Reconcile.ReportDir, "RECONCILE_REPORT_DIR")
+	l.duration(&c.Snapshots.Interval, "BALANCE_SNAPSHOT_INTERVAL")
+	l.int(&c.Snapshots.Every, "BALANCE_SNAPSHOT_EVERY")
+	l.duration(&c.Idempotency.Lease, "IDEMPOTENCY_LEASE")
+	l.duration(&c.Idempotency.Retention, "IDEMPOTENCY_RETENTION")
+	l.duration(&c.Idempotency.SweepInterval, "IDEMPOTENCY_SWEEP_INTERVAL")
+	l.level(&c.LogLevel, "LOG_LEVEL")
+	l.bool(&c.AutoCreateWallets, "AUTO_CREATE_WALLETS")
+	l.bool(&c.MigrateOnStart, "MIGRATE_ON_START")
+	l.duration(&c.HoldExpiryInterval, "HOLD_EXPIRY_INTERVAL")
+
+	return errors.Join(l.errs...)
+}
+
+func (c *Config) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("wallet", flag.ContinueOnError)
+	fs.IntVar(&c.Server.Port, "port", c.Server.Port, "HTTP listen port")
+	fs.DurationVar(&c.Server.ReadTimeout, "http-read-timeout", c.Server.ReadTimeout, "HTTP server read timeout")
+	fs.DurationVar(&c.Server.WriteTimeout, "http-write-timeout", c.Server.WriteTimeout, "HTTP server write timeout")
+	fs.DurationVar(&c.Server.IdleTimeout, "http-idle-timeout", c.Server.IdleTimeout, "HTTP server keep-alive idle timeout")
+	fs.DurationVar(&c.Server.ShutdownTimeout, "shutdown-timeout", c.Server.ShutdownTimeout, "grace period for in-flight requests on shutdown")
+	fs.DurationVar(&c.Server.DrainDelay, "shutdown-drain-delay", c.Server.DrainDelay, "how long /readyz reports draining before the server stops accepting connections")
+	fs.DurationVar(&c.Server.HealthTimeout, "health-check-timeout", c.Server.HealthTimeout, "timeout for readiness dependency checks")
+	fs.StringVar(&c.Server.GinMode, "gin-mode", c.Server.GinMode, "gin mode: debug, release or test")
+	fs.StringVar(&c.Database.URL, "database-url", c.Database.URL, "PostgreSQL connection string")
+	fs.IntVar(&c.Database.MaxOpenConns, "db-max-open-conns", c.Database.MaxOpenConns, "maximum open database connections")
+	fs.IntVar(&c.Database.MaxIdleConns, "db-max-idle-conns", c.Database.MaxIdleConns, "maximum idle database connections")
+	fs.DurationVar(&c.Database.ConnMaxLifetime, "db-conn-max-lifetime", c.Database.ConnMaxLifetime, "maximum connection lifetime, 0 means unlimited")
+	fs.DurationVar(&c.Database.ConnMaxIdleTime, "db-conn-max-idle-time", c.Database.ConnMaxIdleTime, "maximum connection idle time, 0 means unlimited")
+	fs.StringVar(&c.Tracing.Exporter, "tracing-exporter", c.Tracing.Exporter, "trace exporter: none, otlp or stdout")
+	fs.StringVar(&c.Tracing.Endpoint, "tracing-otlp-endpoint", c.Tracing.Endpoint, "OTLP/HTTP collector URL")
+	fs.BoolVar(&c.Tracing.Insecure, "tracing-otlp-insecure", c.Tracing.Insecure, "use plain HTTP for the OTLP collector")
+	fs.StringVar(&c.Tracing.File, "tracing-file", c.Tracing.File, "file for the stdout exporter, empty means stdout")
+	fs.Float64Var(&c.Tracing.SampleRatio, "tracing-sample-ratio", c.Tracing.SampleRatio, "fraction of new traces to sample")
+	fs.BoolVar(&c.Auth.Enabled, "auth-enabled", c.Auth.Enabled, "require API key or JWT authentication")
+	fs.StringVar(&c.Auth.JWT.JWKSURL, "auth-jwks-url", c.Auth.JWT.JWKSURL, "URL of the JWK set used to verify JWTs")
+	fs.StringVar(&c.Auth.JWT.JWKSFile, "auth-jwks-file", c.Auth.JWT.JWKSFile, "file with the JWK set used to verify JWTs")
+	fs.StringVar(&c.Auth.JWT.Issuer, "auth-jwt-issuer", c.Auth.JWT.Issuer, "required JWT issuer")
+	fs.StringVar(&c.Auth.JWT.Audience, "auth-jwt-audience", c.Auth.JWT.Audience, "required JWT audience")
+	fs.StringVar(&c.RateLimit.Backend, "rate-limit-backend", c.RateLimit.Backend, "rate limit bucket storage: none, memory or postgres")
+	fs.Float64Var(&c.RateLimit.Client.Rate, "rate-limit-client-rps", c.RateLimit.Client.Rate, "sustained requests per second per client, 0 disables the limit")
+	fs.IntVar(&c.RateLimit.Client.Burst, "rate-limit-client-burst", c.RateLimit.Client.Burst, "request burst allowed per client")
+	fs.Float64Var(&c.RateLimit.Wallet.Rate, "rate-limit-wallet-rps", c.RateLimit.Wallet.Rate, "sustained requests per second per client and wallet, 0 disables the limit")
+	fs.IntVar(&c.RateLimit.Wallet.Burst, "rate-limit-wallet-burst", c.RateLimit.Wallet.Burst, "request burst allowed per client and wallet")
+	fs.StringVar(&c.Outbox.Sink, "outbox-sink", c.Outbox.Sink, "where balance-change events are published: none, webhook, kafka or file")
+	fs.StringVar(&c.Outbox.WebhookURL, "outbox-webhook-url", c.Outbox.WebhookURL, "URL events are POSTed to by the webhook sink")
+	fs.StringVar(&c.Outbox.KafkaBrokers, "outbox-kafka-brokers", c.Outbox.KafkaBrokers, "comma-separated Kafka broker addresses")
+	fs.StringVar(&c.Outbox.KafkaTopic, "outbox-kafka-topic", c.Outbox.KafkaTopic, "Kafka topic for events")
+	fs.StringVar(&c.Outbox.File, "outbox-file", c.Outbox.File, "file the file sink appends events to")
+	fs.DurationVar(&c.Outbox.PollInterval, "outbox-poll-interval", c.Outbox.PollInterval, "how often the outbox is polled, also the first retry delay")
+	fs.IntVar(&c.Outbox.BatchSize, "outbox-batch-size", c.Outbox.BatchSize, "maximum events relayed per poll")
+	fs.DurationVar(&c.Outbox.PublishTimeout, "outbox-publish-timeout", c.Outbox.PublishTimeout, "timeout for publishing one event")
+	fs.DurationVar(&c.Outbox.MaxBackoff, "outbox-max-backoff", c.Outbox.MaxBackoff, "maximum delay between retries of a failing event")
+	fs.DurationVar(&c.Outbox.Retention, "outbox-retention", c.Outbox.Retention, "how long published events are kept")
+	fs.BoolVar(&c.Webhooks.Enabled, "webhooks-enabled", c.Webhooks.Enabled, "deliver events to merchant webhook subscriptions")
+	fs.DurationVar(&c.Webhooks.PollInterval, "webhook-poll-interval", c.Webhooks.PollInterval, "how often due webhook deliveries are sent, also the first retry delay")
+	fs.IntVar(&c.Webhooks.BatchSize, "webhook-batch-size", c.Webhooks.BatchSize, "maximum webhook deliveries sent per poll")
+	fs.DurationVar(&c.Webhooks.Timeout, "webhook-timeout", c.Webhooks.Timeout, "timeout for one webhook request")
+	fs.IntVar(&c.Webhooks.MaxAttempts, "webhook-max-attempts", c.Webhooks.MaxAttempts, "attempts before a webhook delivery is dead-lettered")
+	fs.DurationVar(&c.Webhooks.MaxBackoff, "webhook-max-backoff", c.Webhooks.MaxBackoff, "maximum delay between webhook retries")
+	fs.BoolVar(&c.Webhooks.AllowPrivateTargets, "webhook-allow-private-targets", c.Webhooks.AllowPrivateTargets, "allow webhooks to loopback and private addresses, for local development only")
+	fs.DurationVar(&c.Reconcile.Interval, "reconcile-interval", c.Reconcile.Interval, "how often stored balances are reconciled with the ledger, 0 disables the job")
+	fs.BoolVar(&c.Reconcile.Repair, "reconcile-repair", c.Reconcile.Repair, "reset balances that differ from the ledger during scheduled runs")
+	fs.StringVar(&c.Reconcile.ReportDir, "reconcile-report-dir", c.Reconcile.ReportDir, "directory reconciliation reports are written to, empty disables report files")
+	fs.DurationVar(&c.Snapshots.Interval, "balance-snapshot-interval", c.Snapshots.Interval, "how often balance snapshots are taken, 0 disables the job")
+	fs.IntVar(&c.Snapshots.Every, "balance-snapshot-every", c.Snapshots.Every, "operations since the latest snapshot that trigger a new one")
+	fs.DurationVar(&c.Idempotency.Lease, "idempotency-lease", c.Idempotency.Lease, "how long an unfinished idempotency key reservation blocks retries")
+	fs.DurationVar(&c.Idempotency.Retention, "idempotency-retention", c.Idempotency.Retention, "how long idempotency keys and their responses are kept")
+	fs.DurationVar(&c.Idempotency.SweepInterval, "idempotency-sweep-interval", c.Idempotency.SweepInterval, "how often expired idempotency keys are deleted")
+	fs.TextVar(&c.LogLevel, "log-level", c.LogLevel, "log level: debug, info, warn or error")
+	fs.BoolVar(&c.AutoCreateWallets, "auto-create-wallets", c.AutoCreateWallets, "create unknown wallets on first deposit")
+	fs.BoolVar(&c.MigrateOnStart, "migrate-on-start", c.MigrateOnStart, "apply pending migrations on startup")
+	fs.DurationVar(&c.HoldExpiryInterval, "hold-expiry-interval", c.HoldExpiryInterval, "how often expired holds are released")
+	return fs
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "SERVER_PORT must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "HTTP_READ_TIMEOUT must be positive, got %s", c.Server.ReadTimeout)
+	check(c.Server.WriteTimeout > 0, "HTTP_WRITE_TIMEOUT must be positive, got %s", c.Server.WriteTimeout)
+	check(c.Server.IdleTimeout > 0, "HTTP_IDLE_TIMEOUT must be positive, got %s", c.Server.IdleTimeout)
+	check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive, got %s", c.Server.ShutdownTimeout)
+	check(c.Server.DrainDelay >= 0, "SHUTDOWN_DRAIN_DELAY must not be negative, got %s", c.Server.DrainDelay)
+	check(c.Server.HealthTimeout > 0, "HEALTH_CHECK_TIMEOUT must be positive, got %s", c.Server.HealthTimeout)
+	check(c.Server.GinMode == gin.DebugMode || c.Server.GinMode == gin.ReleaseMode || c.Server.GinMode == gin.TestMode,
+		"GIN_MODE must be one of debug, release, test, got %q", c.Server.GinMode)
+	check(c.Database.URL != "", "DATABASE_URL is not set")
+	check(c.Database.MaxOpenConns > 0, "DB_MAX_OPEN_CONNS must be positive, got %d", c.Database.MaxOpenConns)
+	check(c.Database.MaxIdleConns >= 0 && c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS (%d), got %d", c.Database.MaxOpenConns, c.Database.MaxIdleConns)
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative, got %s", c.Database.ConnMaxLifetime)
+	check(c.Database.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME must not be negative, got %s", c.Database.ConnMaxIdleTime)
+	check(c.Tracing.Exporter == tracing.ExporterNone || c.Tracing.Exporter == tracing.ExporterOTLP || c.Tracing.Exporter == tracing.ExporterStdout,
+		"TRACING_EXPORTER must be one of none, otlp, stdout, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != tracing.ExporterOTLP || c.Tracing.Endpoint != "", "TRACING_OTLP_ENDPOINT is required for the otlp exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	check(c.Auth.JWT.JWKSURL == "" || c.Auth.JWT.JWKSFile == "", "only one of AUTH_JWKS_URL and AUTH_JWKS_FILE may be set")
+	check(c.RateLimit.Backend == ratelimit.BackendNone || c.RateLimit.Backend == ratelimit.BackendMemory || c.RateLimit.Backend == ratelimit.BackendPostgres,
+		"RATE_LIMIT_BACKEND must be one of none, memory, postgres, got %q", c.RateLimit.Backend)
+	check(c.RateLimit.Client.Rate >= 0, "RATE_LIMIT_CLIENT_RPS must not be negative, got %v", c.RateLimit.Client.Rate)
+	check(c.RateLimit.Client.Burst >= 0, "RATE_LIMIT_CLIENT_BURST must not be negative, got %d", c.RateLimit.Client.Burst)
+	check(c.RateLimit.Wallet.Rate >= 0, "RATE_LIMIT_WALLET_RPS must not be negative, got %v", c.RateLimit.Wallet.Rate)
+	check(c.RateLimit.Wallet.Burst >= 0, "RATE_LIMIT_WALLET_BURST must not be negative, got %d", c.RateLimit.Wallet.Burst)
+	check(c.Outbox.Sink == outbox.SinkNone || c.Outbox.Sink == outbox.SinkWebhook || c.Outbox.Sink == outbox.SinkKafka || c.Outbox.Sink == outbox.SinkFile,
+		"OUTBOX_SINK must be one of none, webhook, kafka, file, got %q", c.Outbox.Sink)
+	check(c.Outbox.Sink != outbox.SinkWebhook || c.Outbox.WebhookURL != "", "OUTBOX_WEBHOOK_URL is required for the webhook sink")
+	check(c.Outbox.Sink != outbox.SinkKafka || (c.Outbox.KafkaBrokers != "" && c.Outbox.KafkaTopic != ""),
+		"OUTBOX_KAFKA_BROKERS and OUTBOX_KAFKA_TOPIC are required for the kafka sink")
+	check(c.Outbox.Sink != outbox.SinkFile || c.Outbox.File != "", "OUTBOX_FILE is required for the file sink")
+	check(c.Outbox.PollInterval > 0, "OUTBOX_POLL_INTERVAL must be positive, got %s", c.Outbox.PollInterval)
+	check(c.Outbox.BatchSize > 0, "OUTBOX_BATCH_SIZE must be positive, got %d", c.Outbox.BatchSize)
+	check(c.Outbox.PublishTimeout > 0, "OUTBOX_PUBLISH_TIMEOUT must be positive, got %s", c.Outbox.PublishTimeout)
+	check(c.Outbox.MaxBackoff >= c.Outbox.PollInterval, "OUTBOX_MAX_BACKOFF must not be less than OUTBOX_POLL_INTERVAL, got %s", c.Outbox.MaxBackoff)
+	check(c.Outbox.Retention > 0, "OUTBOX_RETENTION must be positive, got %s", c.Outbox.Retention)
+	check(c.Webhooks.PollInterval > 0, "WEBHOOK_POLL_INTERVAL must be positive, got %s", c.Webhooks.PollInterval)
+	check(c.Webhooks.BatchSize > 0, "WEBHOOK_BATCH_SIZE must be positive, got %d", c.Webhooks.BatchSize)
+	check(c.Webhooks.Timeout > 0, "WEBHOOK_TIMEOUT must be positive, got %s", c.Webhooks.Timeout)
+	check(c.Webhooks.MaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS must be positive, got %d", c.Webhooks.MaxAttempts)
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.PollInterval, "WEBHOOK_MAX_BACKOFF must not be less than WEBHOOK_POLL_INTERVAL, got %s", c.Webhooks.MaxBackoff)
+	check(c.Reconcile.Interval >= 0, "RECONCILE_INTERVAL must not be negative, got %s", c.Reconcile.Interval)
+	check(c.Snapshots.Interval >= 0, "BALANCE_SNAPSHOT_INTERVAL must not be negative, got %s", c.Snapshots.Interval)
+	check(c.Snapshots.Every > 0, "BALANCE_SNAPSHOT_EVERY must be positive, got %d", c.Snapshots.Every)
+	check(c.Idempotency.Lease >= c.Server.WriteTimeout, "IDEMPOTENCY_LEASE must not be less than HTTP_WRITE_TIMEOUT (%s), got %s", c.Server.WriteTimeout, c.Idempotency.Lease)
+	check(c.Idempotency.Retention > c.Idempotency.Lease, "IDEMPOTENCY_RETENTION must be greater than IDEMPOTENCY_LEASE, got %s", c.Idempotency.Retention)
+	check(c.Idempotency.SweepInterval > 0, "IDEMPOTENCY_SWEEP_INTERVAL must be positive, got %s", c.Idempotency.SweepInterval)
+	check(c.HoldExpiryInterval > 0, "HOLD_EXPIRY_INTERVAL must be positive, got %s", c.HoldExpiryInterval)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+type loader struct {
+	lookup func(string) (string, bool)
+	errs   []error
+}
+
+func (l *loader) string(dst *string, key string) {
+	if value, ok := l.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (l *loader) int(dst *int, key string) {
+	if value, ok := l.lookup(key); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("invalid %s value %q: must be an integer", key, value))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (l *loader) float(dst *float64, key string) {
+	if value, ok := l.lookup(key); ok {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("invalid %s value %q: must be a number", key, value))
+			return
+		}
+		*dst = f
+	}
+}
+
+func (l *loader) bool(dst *bool, key string) {
+	if value, ok := l.lookup(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("invalid %s value %q: must be true or false", key, value))
+			return
+		}
+		*dst = b
+	}
+}
+
+func (l *loader) duration(dst *time.Duration, key string) {
+	if value, ok := l.lookup(key); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("invalid %s value %q: must be a duration such as 30s", key, value))
+			return
+		}
+		*dst = d
+	}
+}
+
+func (l *loader) level(dst *slog.Level, key string) {
+	if value, ok := l.lookup(key); ok {
+		if err := dst.UnmarshalText([]byte(value)); err != nil {
+			l.errs = append(l.errs, fmt.Errorf("invalid %s value %q: must be debug, info, warn or error", key, value))
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	writeConfigFile(t, "DATABASE_URL=postgres://file\nDB_MAX_OPEN_CONNS=40\nHTTP_READ_TIMEOUT=3s\nLOG_LEVEL=debug\n")
+	t.Setenv("DB_MAX_OPEN_CONNS", "50")
+
+	cfg, args, err := Load([]string{"-db-max-open-conns", "60", "-gin-mode", "release", "migrate", "up"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Database.URL != "postgres://file" {
+		t.Errorf("DATABASE_URL should come from the file, got %q", cfg.Database.URL)
+	}
+	if cfg.Database.MaxOpenConns != 60 {
+		t.Errorf("flag should override env and file, got %d", cfg.Database.MaxOpenConns)
+	}
+	if cfg.Server.ReadTimeout != 3*time.Second {
+		t.Errorf("HTTP_READ_TIMEOUT = %s, want 3s", cfg.Server.ReadTimeout)
+	}
+	if cfg.LogLevel != slog.LevelDebug || cfg.Server.GinMode != "release" {
+		t.Errorf("unexpected log level %s or gin mode %s", cfg.LogLevel, cfg.Server.GinMode)
+	}
+	if cfg.Database.MaxIdleConns != 5 {
+		t.Errorf("unset values should keep defaults, got DB_MAX_IDLE_CONNS %d", cfg.Database.MaxIdleConns)
+	}
+	if strings.Join(args, " ") != "migrate up" {
+		t.Errorf("remaining args = %v, want [migrate up]", args)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "missing database url",
+			file:    "SERVER_PORT=8080\n",
+			wantErr: "DATABASE_URL is not set",
+		},
+		{
+			name:    "malformed duration",
+			file:    "DATABASE_URL=postgres://x\nSHUTDOWN_TIMEOUT=5\n",
+			wantErr: "invalid SHUTDOWN_TIMEOUT",
+		},
+		{
+			name:    "idle above open",
+			file:    "DATABASE_URL=postgres://x\nDB_MAX_OPEN_CONNS=2\nDB_MAX_IDLE_CONNS=3\n",
+			wantErr: "DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS",
+		},
+		{
+			name:    "unknown gin mode",
+			file:    "DATABASE_URL=postgres://x\nGIN_MODE=prod\n",
+			wantErr: "GIN_MODE must be one of",
+		},
+		{
+			name:    "unknown rate limit backend",
+			file:    "DATABASE_URL=postgres://x\nRATE_LIMIT_BACKEND=redis\n",
+			wantErr: "RATE_LIMIT_BACKEND must be one of",
+		},
+		{
+			name:    "unknown log level",
+			file:    "DATABASE_URL=postgres://x\nLOG_LEVEL=verbose\n",
+			wantErr: "invalid LOG_LEVEL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfigFile(t, tt.file)
+
+			_, _, err := Load(nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/service"
+)
+
+type APIKeyHandler struct {
+	service *service.APIKeyService
+	responder
+}
+
+func NewAPIKeyHandler(service *service.APIKeyService, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		service:   service,
+		responder: responder{logger: logger},
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Principal string `json:"principal" binding:"required"`
+	Role      string `json:"role"`
+}
+
+type WalletOwnerRequest struct {
+	Principal string `json:"principal" binding:"required"`
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	role := models.RoleClient
+	if req.Role != "" {
+		role = models.Role(req.Role)
+	}
+
+	key, err := h.service.CreateAPIKey(c.Request.Context(), req.Name, req.Principal, role)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(201, key)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("KEY_ID"))
+	if err != nil {
+		respondBadRequest(c, CodeInvalidAPIKeyID, "invalid api key ID")
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(c.Request.Context(), id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.Status(204)
+}
+
+func (h *WalletHandler) AddWalletOwner(c *gin.Context) {
+	walletID, ok := parseWalletID(c)
+	if !ok {
+		return
+	}
+
+	var req WalletOwnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.service.AddWalletOwner(c.Request.Context(), walletID, req.Principal); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.Status(204)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/models"
+)
+
+// BatchRequest only checks the shape of the batch. Items are validated one
+// by one in the service, so a bad item fails on its own in BEST_EFFORT mode.
+type BatchRequest struct {
+	Mode       models.BatchMode   `json:"mode"`
+	Operations []OperationRequest `json:"operations" binding:"required,min=1"`
+}
+
+type BatchResponse struct {
+	Mode      models.BatchMode      `json:"mode"`
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Results   []BatchResultResponse `json:"results"`
+}
+
+type BatchResultResponse struct {
+	Index     int                     `json:"index"`
+	Operation *models.WalletOperation `json:"operation,omitempty"`
+	Error     *ErrorResponse          `json:"error,omitempty"`
+}
+
+// BatchErrorResponse is returned when an item fails an atomic batch. Index
+// is the position of the failing item in the request.
+type BatchErrorResponse struct {
+	ErrorResponse
+	Index int `json:"index"`
+}
+
+// ProcessBatch applies up to models.MaxBatchSize deposits and withdrawals.
+// Mode defaults to ATOMIC.
+func (h *WalletHandler) ProcessBatch(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	mode := models.BatchModeAtomic
+	if req.Mode != "" {
+		mode = models.BatchMode(strings.ToUpper(string(req.Mode)))
+	}
+
+	items := make([]models.BatchItem, len(req.Operations))
+	for i, op := range req.Operations {
+		currency, err := models.ParseCurrency(op.Currency)
+		if err != nil {
+			// Keep the unparseable code so the service rejects this item
+			// according to the batch mode.
+			currency = models.Currency(op.Currency)
+		}
+		items[i] = models.BatchItem{
+			WalletID:  op.WalletID,
+			Operation: op.OperationType,
+			Currency:  currency,
+			Amount:    op.Amount,
+		}
+	}
+
+	results, err := h.service.ProcessBatch(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), mode, items, h.autoCreateWallets)
+	if err != nil {
+		var itemErr *models.BatchItemError
+		if errors.As(err, &itemErr) {
+			if status, body, ok := domainError(itemErr.Err); ok {
+				c.AbortWithStatusJSON(status, BatchErrorResponse{ErrorResponse: body, Index: itemErr.Index})
+				return
+			}
+		}
+		h.respondError(c, err)
+		return
+	}
+
+	response := BatchResponse{Mode: mode, Results: make([]BatchResultResponse, len(results))}
+	for i, result := range results {
+		response.Results[i] = BatchResultResponse{Index: result.Index, Operation: result.Operation}
+		if result.Err == nil {
+			response.Succeeded++
+			continue
+		}
+
+		response.Failed++
+		_, body, ok := domainError(result.Err)
+		if !ok {
+			h.logger.ErrorContext(c.Request.Context(), "batch item failed",
+				slog.Int("index", result.Index), slog.Any("error", result.Err))
+			body = ErrorResponse{Code: CodeInternal, Error: "internal server error"}
+		}
+		response.Results[i].Error = &body
+	}
+
+	c.JSON(200, response)
+}
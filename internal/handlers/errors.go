@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/models"
+)
+
+const (
+	CodeInvalidRequest     = "INVALID_REQUEST"
+	CodeInvalidWalletID    = "INVALID_WALLET_ID"
+	CodeWalletNotFound     = "WALLET_NOT_FOUND"
+	CodeWalletExists       = "WALLET_ALREADY_EXISTS"
+	CodeWalletFrozen       = "WALLET_FROZEN"
+	CodeWalletDebitBlock   = "WALLET_DEBIT_BLOCKED"
+	CodeWalletClosed       = "WALLET_CLOSED"
+	CodeWalletNotEmpty     = "WALLET_NOT_EMPTY"
+	CodeInvalidStatus      = "INVALID_STATUS"
+	CodeInsufficientFunds  = "INSUFFICIENT_FUNDS"
+	CodeBalanceOverflow    = "BALANCE_OVERFLOW"
+	CodeInvalidOperation   = "INVALID_OPERATION"
+	CodeInvalidAmount      = "INVALID_AMOUNT"
+	CodeInvalidCurrency    = "INVALID_CURRENCY"
+	CodeSameWallet         = "SAME_WALLET"
+	CodeInvalidCursor      = "INVALID_CURSOR"
+	CodeInvalidTimeRange   = "INVALID_TIME_RANGE"
+	CodeFutureBalanceTime  = "FUTURE_BALANCE_TIME"
+	CodeInvalidHoldID      = "INVALID_HOLD_ID"
+	CodeHoldNotFound       = "HOLD_NOT_FOUND"
+	CodeHoldNotActive      = "HOLD_NOT_ACTIVE"
+	CodeCaptureExceeds     = "CAPTURE_EXCEEDS_HOLD"
+	CodeInvalidHoldTTL     = "INVALID_HOLD_TTL"
+	CodeLimitExceeded      = "LIMIT_EXCEEDED"
+	CodeInvalidLimits      = "INVALID_LIMITS"
+	CodeLimitsNotFound     = "LIMITS_NOT_FOUND"
+	CodeUnauthenticated    = "UNAUTHENTICATED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeAPIKeyNotFound     = "API_KEY_NOT_FOUND"
+	CodeInvalidAPIKeyID    = "INVALID_API_KEY_ID"
+	CodeInvalidRole        = "INVALID_ROLE"
+	CodeInvalidPrincipal   = "INVALID_PRINCIPAL"
+	CodeInvalidBatchMode   = "INVALID_BATCH_MODE"
+	CodeInvalidBatchSize   = "INVALID_BATCH_SIZE"
+	CodeInvalidWebhookID   = "INVALID_WEBHOOK_ID"
+	CodeWebhookNotFound    = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound   = "DELIVERY_NOT_FOUND"
+	CodeInvalidWebhookURL  = "INVALID_WEBHOOK_URL"
+	CodeInvalidEventType   = "INVALID_EVENT_TYPE"
+	CodeInvalidOperationID = "INVALID_OPERATION_ID"
+	CodeOperationNotFound  = "OPERATION_NOT_FOUND"
+	CodeNotReversible      = "NOT_REVERSIBLE"
+	CodeReversalExceeds    = "REVERSAL_EXCEEDS_OPERATION"
+	CodeAlreadyReversed    = "ALREADY_REVERSED"
+	CodeKeyInProgress      = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeInternal           = "INTERNAL_ERROR"
+)
+
+type ErrorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{models.ErrWalletNotFound, 404, CodeWalletNotFound},
+	{models.ErrWalletExists, 409, CodeWalletExists},
+	{models.ErrWalletFrozen, 409, CodeWalletFrozen},
+	{models.ErrWalletDebitBlock, 409, CodeWalletDebitBlock},
+	{models.ErrWalletClosed, 409, CodeWalletClosed},
+	{models.ErrWalletNotEmpty, 409, CodeWalletNotEmpty},
+	{models.ErrInvalidStatus, 400, CodeInvalidStatus},
+	{models.ErrInsufficientFunds, 400, CodeInsufficientFunds},
+	{models.ErrBalanceOverflow, 400, CodeBalanceOverflow},
+	{models.ErrInvalidWalletID, 400, CodeInvalidWalletID},
+	{models.ErrInvalidOperation, 400, CodeInvalidOperation},
+	{models.ErrInvalidAmount, 400, CodeInvalidAmount},
+	{models.ErrInvalidCurrency, 400, CodeInvalidCurrency},
+	{models.ErrSameWallet, 400, CodeSameWallet},
+	{models.ErrInvalidCursor, 400, CodeInvalidCursor},
+	{models.ErrInvalidTimeRange, 400, CodeInvalidTimeRange},
+	{models.ErrFutureBalanceTime, 400, CodeFutureBalanceTime},
+	{models.ErrHoldNotFound, 404, CodeHoldNotFound},
+	{models.ErrHoldNotActive, 409, CodeHoldNotActive},
+	{models.ErrCaptureExceeds, 400, CodeCaptureExceeds},
+	{models.ErrInvalidHoldTTL, 400, CodeInvalidHoldTTL},
+	{models.ErrLimitExceeded, 400, CodeLimitExceeded},
+	{models.ErrInvalidLimits, 400, CodeInvalidLimits},
+	{models.ErrLimitsNotFound, 404, CodeLimitsNotFound},
+	{models.ErrUnauthenticated, 401, CodeUnauthenticated},
+	{models.ErrForbidden, 403, CodeForbidden},
+	{models.ErrAPIKeyNotFound, 404, CodeAPIKeyNotFound},
+	{models.ErrInvalidRole, 400, CodeInvalidRole},
+	{models.ErrInvalidPrincipal, 400, CodeInvalidPrincipal},
+	{models.ErrInvalidBatchMode, 400, CodeInvalidBatchMode},
+	{models.ErrInvalidBatchSize, 400, CodeInvalidBatchSize},
+	{models.ErrWebhookNotFound, 404, CodeWebhookNotFound},
+	{models.ErrDeliveryNotFound, 404, CodeDeliveryNotFound},
+	{models.ErrInvalidWebhookURL, 400, CodeInvalidWebhookURL},
+	{models.ErrWebhookURLNotPublic, 400, CodeInvalidWebhookURL},
+	{models.ErrInvalidEventType, 400, CodeInvalidEventType},
+	{models.ErrOperationNotFound, 404, CodeOperationNotFound},
+	{models.ErrNotReversible, 400, CodeNotReversible},
+	{models.ErrReversalExceeds, 400, CodeReversalExceeds},
+	{models.ErrAlreadyReversed, 409, CodeAlreadyReversed},
+	{models.ErrIdempotencyKeyLost, 409, CodeKeyInProgress},
+}
+
+// responder is embedded by handlers to share error rendering and logging.
+type responder struct {
+	logger *slog.Logger
+}
+
+// domainError looks up the HTTP status and response body for a domain error.
+func domainError(err error) (int, ErrorResponse, bool) {
+	for _, de := range domainErrors {
+		if errors.Is(err, de.err) {
+			return de.status, ErrorResponse{Code: de.code, Error: de.err.Error()}, true
+		}
+	}
+
+	return 0, ErrorResponse{}, false
+}
+
+func (h responder) respondError(c *gin.Context, err error) {
+	if status, body, ok := domainError(err); ok {
+		c.AbortWithStatusJSON(status, body)
+		return
+	}
+
+	h.logger.ErrorContext(c.Request.Context(), "unhandled error",
+		slog.String("method", c.Request.Method), slog.String("route", c.FullPath()), slog.Any("error", err))
+	c.AbortWithStatusJSON(500, ErrorResponse{Code: CodeInternal, Error: "internal server error"})
+}
+
+func respondBadRequest(c *gin.Context, code string, message string) {
+	c.AbortWithStatusJSON(400, ErrorResponse{Code: code, Error: message})
+}
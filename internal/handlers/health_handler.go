@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	checkStatusOK          = "ok"
+	checkStatusUnavailable = "unavailable"
+)
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+type MigrationChecker interface {
+	Pending(ctx context.Context) (int, error)
+}
+
+type HealthHandler struct {
+	db         Pinger
+	migrations MigrationChecker
+	timeout    time.Duration
+	draining   atomic.Bool
+}
+
+func NewHealthHandler(db Pinger, migrations MigrationChecker, timeout time.Duration) *HealthHandler {
+	return &HealthHandler{
+		db:         db,
+		migrations: migrations,
+		timeout:    timeout,
+	}
+}
+
+type HealthCheck struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Pending   *int   `json:"pending,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// SetDraining makes the readiness probe fail so traffic is routed away
+// before the server stops accepting connections.
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(200, HealthResponse{Status: checkStatusOK})
+}
+
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	checks := map[string]HealthCheck{
+		"database":   h.checkDatabase(ctx),
+		"migrations": h.checkMigrations(ctx),
+		"shutdown":   {Status: checkStatusOK},
+	}
+	if h.draining.Load() {
+		checks["shutdown"] = HealthCheck{Status: checkStatusUnavailable, Error: "server is shutting down"}
+	}
+
+	response := HealthResponse{Status: checkStatusOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != checkStatusOK {
+			response.Status = checkStatusUnavailable
+		}
+	}
+
+	if response.Status != checkStatusOK {
+		c.JSON(503, response)
+		return
+	}
+	c.JSON(200, response)
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) HealthCheck {
+	start := time.Now()
+	err := h.db.PingContext(ctx)
+	check := HealthCheck{Status: checkStatusOK, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		check.Status, check.Error = checkStatusUnavailable, err.Error()
+	}
+	return check
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) HealthCheck {
+	start := time.Now()
+	pending, err := h.migrations.Pending(ctx)
+	check := HealthCheck{Status: checkStatusOK, LatencyMs: time.Since(start).Milliseconds(), Pending: &pending}
+	switch {
+	case err != nil:
+		check.Status, check.Error, check.Pending = checkStatusUnavailable, err.Error(), nil
+	case pending > 0:
+		check.Status, check.Error = checkStatusUnavailable, "database schema is behind the embedded migrations"
+	}
+	return check
+}
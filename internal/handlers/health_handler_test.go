@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakePinger struct{ err error }
+
+func (p fakePinger) PingContext(ctx context.Context) error { return p.err }
+
+type fakeMigrations struct {
+	pending int
+	err     error
+}
+
+func (m fakeMigrations) Pending(ctx context.Context) (int, error) { return m.pending, m.err }
+
+func TestHealthHandler_Readiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		db         fakePinger
+		migrations fakeMigrations
+		draining   bool
+		wantStatus int
+		wantFailed string
+	}{
+		{name: "ready", wantStatus: 200},
+		{name: "database down", db: fakePinger{err: errors.New("connection refused")}, wantStatus: 503, wantFailed: "database"},
+		{name: "pending migrations", migrations: fakeMigrations{pending: 2}, wantStatus: 503, wantFailed: "migrations"},
+		{name: "draining", draining: true, wantStatus: 503, wantFailed: "shutdown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(tt.db, tt.migrations, time.Second)
+			if tt.draining {
+				h.SetDraining()
+			}
+
+			router := gin.New()
+			router.GET("/readyz", h.Readiness)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			var body HealthResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON body: %v", err)
+			}
+			for name, check := range body.Checks {
+				if failed := check.Status != checkStatusOK; failed != (name == tt.wantFailed) {
+					t.Errorf("check %s status = %s, failed check want %q", name, check.Status, tt.wantFailed)
+				}
+			}
+		})
+	}
+}
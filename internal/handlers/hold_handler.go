@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+type CreateHoldRequest struct {
+	Amount     int64  `json:"amount" binding:"required"`
+	Currency   string `json:"currency"`
+	TTLSeconds int64  `json:"ttlSeconds"`
+}
+
+type CaptureHoldRequest struct {
+	Amount *int64 `json:"amount"`
+}
+
+func (h *WalletHandler) CreateHold(c *gin.Context) {
+	walletID, err := uuid.Parse(c.Param("WALLET_UUID"))
+	if err != nil {
+		respondBadRequest(c, CodeInvalidWalletID, "invalid wallet ID")
+		return
+	}
+
+	var req CreateHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	currency, err := models.ParseCurrency(req.Currency)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	if req.TTLSeconds < 0 {
+		h.respondError(c, models.ErrInvalidHoldTTL)
+		return
+	}
+
+	if !h.authorizeWallet(c, walletID) {
+		return
+	}
+
+	hold, err := h.service.CreateHold(c.Request.Context(), walletID, currency, req.Amount, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(201, hold)
+}
+
+func (h *WalletHandler) GetHold(c *gin.Context) {
+	holdID, ok := parseHoldID(c)
+	if !ok {
+		return
+	}
+
+	hold, ok := h.authorizeHold(c, holdID)
+	if !ok {
+		return
+	}
+	c.JSON(200, hold)
+}
+
+func (h *WalletHandler) CaptureHold(c *gin.Context) {
+	holdID, ok := parseHoldID(c)
+	if !ok {
+		return
+	}
+
+	var req CaptureHoldRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBadRequest(c, CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	if _, ok := h.authorizeHold(c, holdID); !ok {
+		return
+	}
+
+	hold, err := h.service.CaptureHold(c.Request.Context(), holdID, req.Amount)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, hold)
+}
+
+func (h *WalletHandler) ReleaseHold(c *gin.Context) {
+	holdID, ok := parseHoldID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := h.authorizeHold(c, holdID); !ok {
+		return
+	}
+
+	hold, err := h.service.ReleaseHold(c.Request.Context(), holdID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, hold)
+}
+
+func (h *WalletHandler) authorizeHold(c *gin.Context, holdID uuid.UUID) (*models.Hold, bool) {
+	hold, err := h.service.GetHold(c.Request.Context(), holdID)
+	if err != nil {
+		h.respondError(c, err)
+		return nil, false
+	}
+
+	return hold, h.authorizeWallet(c, hold.WalletID)
+}
+
+func parseHoldID(c *gin.Context) (uuid.UUID, bool) {
+	holdID, err := uuid.Parse(c.Param("HOLD_ID"))
+	if err != nil {
+		respondBadRequest(c, CodeInvalidHoldID, "invalid hold ID")
+		return uuid.Nil, false
+	}
+
+	return holdID, true
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+type LimitsRequest struct {
+	Currency  string `json:"currency"`
+	MaxSingle *int64 `json:"maxSingle"`
+	Daily     *int64 `json:"daily"`
+	Monthly   *int64 `json:"monthly"`
+}
+
+func (h *WalletHandler) GetDefaultLimits(c *gin.Context) {
+	h.getLimits(c, nil)
+}
+
+func (h *WalletHandler) SetDefaultLimits(c *gin.Context) {
+	h.setLimits(c, nil)
+}
+
+func (h *WalletHandler) DeleteDefaultLimits(c *gin.Context) {
+	h.deleteLimits(c, nil)
+}
+
+func (h *WalletHandler) GetWalletLimits(c *gin.Context) {
+	if walletID, ok := parseWalletID(c); ok {
+		h.getLimits(c, &walletID)
+	}
+}
+
+func (h *WalletHandler) SetWalletLimits(c *gin.Context) {
+	if walletID, ok := parseWalletID(c); ok {
+		h.setLimits(c, &walletID)
+	}
+}
+
+func (h *WalletHandler) DeleteWalletLimits(c *gin.Context) {
+	if walletID, ok := parseWalletID(c); ok {
+		h.deleteLimits(c, &walletID)
+	}
+}
+
+func (h *WalletHandler) getLimits(c *gin.Context, walletID *uuid.UUID) {
+	currency, err := models.ParseCurrency(c.Query("currency"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	limits, err := h.service.GetLimits(c.Request.Context(), walletID, currency)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, limits)
+}
+
+func (h *WalletHandler) setLimits(c *gin.Context, walletID *uuid.UUID) {
+	var req LimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	currency, err := models.ParseCurrency(req.Currency)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	limits, err := h.service.SetLimits(c.Request.Context(), models.WithdrawalLimits{
+		WalletID:  walletID,
+		Currency:  currency,
+		MaxSingle: req.MaxSingle,
+		Daily:     req.Daily,
+		Monthly:   req.Monthly,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, limits)
+}
+
+func (h *WalletHandler) deleteLimits(c *gin.Context, walletID *uuid.UUID) {
+	currency, err := models.ParseCurrency(c.Query("currency"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	if err := h.service.DeleteLimits(c.Request.Context(), walletID, currency); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.Status(204)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/auth"
+)
+
+type ReverseOperationRequest struct {
+	Amount        *int64 `json:"amount"`
+	AllowNegative bool   `json:"allowNegative"`
+}
+
+func (h *WalletHandler) ReverseOperation(c *gin.Context) {
+	operationID, err := uuid.Parse(c.Param("OPERATION_ID"))
+	if err != nil {
+		respondBadRequest(c, CodeInvalidOperationID, "invalid operation ID")
+		return
+	}
+
+	var req ReverseOperationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBadRequest(c, CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	op, err := h.service.GetOperation(c.Request.Context(), operationID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	if !h.authorizeWallet(c, op.WalletID) {
+		return
+	}
+
+	reversal, err := h.service.ReverseOperation(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()),
+		operationID, req.Amount, req.AllowNegative)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(201, reversal)
+}
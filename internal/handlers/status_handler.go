@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/models"
+)
+
+type StatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// SetWalletStatus records the authenticated principal as the actor, so the
+// audit trail cannot be attributed to someone else by the request body.
+
+func (h *WalletHandler) SetWalletStatus(c *gin.Context) {
+	walletID, ok := parseWalletID(c)
+	if !ok {
+		return
+	}
+
+	var req StatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	principal := auth.PrincipalFromContext(c.Request.Context())
+	if principal == nil {
+		h.respondError(c, models.ErrUnauthenticated)
+		return
+	}
+
+	change, err := h.service.SetWalletStatus(c.Request.Context(), walletID, models.WalletStatus(req.Status), req.Reason, principal.ID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, change)
+}
+
+func (h *WalletHandler) ListStatusChanges(c *gin.Context) {
+	walletID, ok := parseWalletID(c)
+	if !ok {
+		return
+	}
+
+	changes, err := h.service.ListStatusChanges(c.Request.Context(), walletID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, changes)
+}
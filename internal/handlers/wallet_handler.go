@@ -1,102 +1,282 @@
 package handlers
 
 import (
+	"errors"
+	"log/slog"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/auth"
 	"github.com/itk/wallet/internal/models"
 	"github.com/itk/wallet/internal/service"
 )
 
 type WalletHandler struct {
-	service *service.WalletService
+	service           *service.WalletService
+	autoCreateWallets bool
+	responder
 }
 
-func NewWalletHandler(service *service.WalletService) *WalletHandler {
+func NewWalletHandler(service *service.WalletService, autoCreateWallets bool, logger *slog.Logger) *WalletHandler {
 	return &WalletHandler{
-		service: service,
+		service:           service,
+		autoCreateWallets: autoCreateWallets,
+		responder:         responder{logger: logger},
 	}
 }
 
 type OperationRequest struct {
 	WalletID      uuid.UUID            `json:"valletId" binding:"required"`
 	OperationType models.OperationType `json:"operationType" binding:"required"`
-	Amount        int                  `json:"amount" binding:"required"`
+	Amount        int64                `json:"amount" binding:"required"`
+	Currency      string               `json:"currency"`
 }
 
-func (h *WalletHandler) GetBalance(c *gin.Context) {
+type CreateWalletRequest struct {
+	ID *uuid.UUID `json:"id"`
+}
+
+type TransferRequest struct {
+	FromWalletID uuid.UUID `json:"fromWalletId" binding:"required"`
+	ToWalletID   uuid.UUID `json:"toWalletId" binding:"required"`
+	Amount       int64     `json:"amount" binding:"required"`
+	Currency     string    `json:"currency"`
+}
+
+func (h *WalletHandler) CreateWallet(c *gin.Context) {
+	var req CreateWalletRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBadRequest(c, CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	walletID := uuid.New()
+	if req.ID != nil {
+		walletID = *req.ID
+	}
+
+	wallet, err := h.service.OpenWallet(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), walletID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(201, wallet)
+}
+
+func (h *WalletHandler) GetWallet(c *gin.Context) {
 	walletIDStr := c.Param("WALLET_UUID")
 
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": "invalid wallet ID"})
+		respondBadRequest(c, CodeInvalidWalletID, "invalid wallet ID")
+		return
+	}
+
+	if !h.authorizeWallet(c, walletID) {
 		return
 	}
 
-	balance, err := h.service.GetBalance(c.Request.Context(), walletID)
+	wallet, err := h.service.GetWallet(c.Request.Context(), walletID)
 	if err != nil {
-		if strings.Contains(err.Error(), "balance not found") || strings.Contains(err.Error(), "not found") {
-			c.AbortWithStatusJSON(404, gin.H{"error": "wallet not found"})
-			return
-		}
-		c.AbortWithStatusJSON(500, gin.H{"error": "internal server error"})
+		h.respondError(c, err)
 		return
 	}
-	c.JSON(200, gin.H{"balance": balance})
+	c.JSON(200, wallet)
+}
+
+// GetBalance returns the balance in one currency, as of the RFC3339 instant
+// in the at query parameter when it is given.
+func (h *WalletHandler) GetBalance(c *gin.Context) {
+	walletID, ok := parseWalletID(c)
+	if !ok {
+		return
+	}
+
+	currency, err := models.ParseCurrency(c.Query("currency"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	at, err := parseTimeQuery(c, "at")
+	if err != nil {
+		respondBadRequest(c, CodeInvalidRequest, "at must be an RFC3339 timestamp")
+		return
+	}
+
+	if !h.authorizeWallet(c, walletID) {
+		return
+	}
+
+	balance, err := h.service.GetBalanceAt(c.Request.Context(), walletID, currency, at)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, balance)
 }
 
 func (h *WalletHandler) ProcessOperation(c *gin.Context) {
 	var req OperationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	if req.Amount <= 0 {
-		c.AbortWithStatusJSON(400, gin.H{"error": "amount must be greater than zero"})
+		h.respondError(c, models.ErrInvalidAmount)
 		return
 	}
 
-	_, err := h.service.UpdateBalance(c.Request.Context(), req.WalletID, req.OperationType, req.Amount)
+	currency, err := models.ParseCurrency(req.Currency)
 	if err != nil {
-		if strings.Contains(err.Error(), "wallet not found") || strings.Contains(err.Error(), "balance not found") {
-			_, err := h.service.CreateWallet(c.Request.Context(), req.WalletID)
-			if err != nil {
-				c.AbortWithStatusJSON(500, gin.H{"error": "failed to create wallet"})
-				return
-			}
+		h.respondError(c, err)
+		return
+	}
 
-			_, err = h.service.UpdateBalance(c.Request.Context(), req.WalletID, req.OperationType, req.Amount)
-			if err != nil {
-				if strings.Contains(err.Error(), "insufficient funds") {
-					c.AbortWithStatusJSON(400, gin.H{"error": "insufficient funds"})
-					return
-				}
-				if strings.Contains(err.Error(), "invalid operationType") || strings.Contains(err.Error(), "amount cannot be zero") {
-					c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
-					return
-				}
-				c.AbortWithStatusJSON(500, gin.H{"error": "internal server error"})
-				return
-			}
-			c.JSON(200, gin.H{"message": "operation completed"})
+	principal := auth.PrincipalFromContext(c.Request.Context())
+	err = h.service.AuthorizeWallet(c.Request.Context(), principal, req.WalletID)
+	if errors.Is(err, models.ErrWalletNotFound) && h.autoCreateWallets && req.OperationType == models.OperationTypeDeposit {
+		_, err = h.service.CreateOwnedWallet(c.Request.Context(), principal, req.WalletID)
+	}
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	_, err = h.service.UpdateBalance(c.Request.Context(), req.WalletID, currency, req.OperationType, req.Amount)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "operation completed"})
+}
+
+func (h *WalletHandler) Transfer(c *gin.Context) {
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	currency, err := models.ParseCurrency(req.Currency)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	if !h.authorizeWallet(c, req.FromWalletID) {
+		return
+	}
+
+	transfer, err := h.service.Transfer(c.Request.Context(), req.FromWalletID, req.ToWalletID, currency, req.Amount)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(200, transfer)
+}
+
+func (h *WalletHandler) ListOperations(c *gin.Context) {
+	walletID, err := uuid.Parse(c.Param("WALLET_UUID"))
+	if err != nil {
+		respondBadRequest(c, CodeInvalidWalletID, "invalid wallet ID")
+		return
+	}
+
+	if !h.authorizeWallet(c, walletID) {
+		return
+	}
+
+	var filter models.OperationFilter
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			respondBadRequest(c, CodeInvalidRequest, "limit must be a positive integer")
 			return
 		}
+		filter.Limit = limit
+	}
 
-		if strings.Contains(err.Error(), "insufficient funds") {
-			c.AbortWithStatusJSON(400, gin.H{"error": "insufficient funds"})
-			return
+	for _, value := range c.QueryArray("type") {
+		for _, t := range strings.Split(value, ",") {
+			operationType := models.OperationType(strings.ToUpper(strings.TrimSpace(t)))
+			if !operationType.IsValid() {
+				respondBadRequest(c, CodeInvalidOperation, "invalid operation type: "+t)
+				return
+			}
+			filter.Types = append(filter.Types, operationType)
 		}
+	}
 
-		if strings.Contains(err.Error(), "invalid operationType") || strings.Contains(err.Error(), "amount cannot be zero") {
-			c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+	if currency := c.Query("currency"); currency != "" {
+		if filter.Currency, err = models.ParseCurrency(currency); err != nil {
+			h.respondError(c, err)
 			return
 		}
+	}
 
-		c.AbortWithStatusJSON(500, gin.H{"error": "internal server error"})
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		respondBadRequest(c, CodeInvalidTimeRange, "from must be an RFC3339 timestamp")
+		return
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		respondBadRequest(c, CodeInvalidTimeRange, "to must be an RFC3339 timestamp")
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "operation completed"})
+	operations, nextCursor, err := h.service.ListOperations(c.Request.Context(), walletID, filter, c.Query("cursor"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response := gin.H{"operations": operations}
+	if nextCursor != "" {
+		response["nextCursor"] = nextCursor
+	}
+	c.JSON(200, response)
+}
+
+// authorizeWallet responds with 401 or 403 and returns false when the caller
+// may not operate on the wallet.
+func (h *WalletHandler) authorizeWallet(c *gin.Context, walletID uuid.UUID) bool {
+	if err := h.service.AuthorizeWallet(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), walletID); err != nil {
+		h.respondError(c, err)
+		return false
+	}
+
+	return true
+}
+
+func parseWalletID(c *gin.Context) (uuid.UUID, bool) {
+	walletID, err := uuid.Parse(c.Param("WALLET_UUID"))
+	if err != nil {
+		respondBadRequest(c, CodeInvalidWalletID, "invalid wallet ID")
+		return uuid.Nil, false
+	}
+
+	return walletID, true
+}
+
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
 }
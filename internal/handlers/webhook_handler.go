@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/service"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+	responder
+}
+
+func NewWebhookHandler(service *service.WebhookService, logger *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service:   service,
+		responder: responder{logger: logger},
+	}
+}
+
+type WebhookRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"eventTypes"`
+}
+
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), req.URL, req.EventTypes)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(201, sub)
+}
+
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subs, err := h.service.ListSubscriptions(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"webhooks": subs})
+}
+
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, sub)
+}
+
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	sub, err := h.service.UpdateSubscription(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), id, req.URL, req.EventTypes)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, sub)
+}
+
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.RotateSecret(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, sub)
+}
+
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.Status(204)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	status := models.DeliveryStatus(strings.ToUpper(c.Query("status")))
+	if status != "" && !status.IsValid() {
+		respondBadRequest(c, CodeInvalidRequest, "status must be one of PENDING, DELIVERED, DEAD")
+		return
+	}
+
+	var limit int
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			respondBadRequest(c, CodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), id, status, limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"deliveries": deliveries})
+}
+
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("DELIVERY_ID"))
+	if err != nil {
+		respondBadRequest(c, CodeInvalidRequest, "invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.service.RetryDelivery(c.Request.Context(), auth.PrincipalFromContext(c.Request.Context()), id, deliveryID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(200, delivery)
+}
+
+func parseWebhookID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("WEBHOOK_ID"))
+	if err != nil {
+		respondBadRequest(c, CodeInvalidWebhookID, "invalid webhook ID")
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wallet"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	Operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Wallet operations by type and outcome.",
+	}, []string{"operation", "outcome"})
+
+	LockWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lock_wait_seconds",
+		Help:      "Time spent waiting for wallet row locks by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by the rate limiter by scope.",
+	}, []string{"scope"})
+
+	OutboxEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_total",
+		Help:      "Outbox events handed to the sink by outcome.",
+	}, []string{"outcome"})
+
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Webhook delivery attempts by outcome.",
+	}, []string{"outcome"})
+
+	BalanceMismatches = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "balance_mismatches",
+		Help:      "Stored balances left differing from the ledger by the last reconciliation run.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration, Operations, LockWait, RateLimited, OutboxEvents, WebhookDeliveries,
+		BalanceMismatches,
+	)
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Middleware records request count and latency. Requests that match no route
+// are grouped under a single label to keep cardinality bounded.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+func ObserveOperation(operation string, err error) {
+	Operations.WithLabelValues(operation, Outcome(err)).Inc()
+}
+
+func ObserveLockWait(operation string, start time.Time) {
+	LockWait.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// Outcome maps an operation error to a low-cardinality label.
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, models.ErrInsufficientFunds):
+		return "insufficient_funds"
+	case errors.Is(err, models.ErrWalletNotFound), errors.Is(err, models.ErrHoldNotFound), errors.Is(err, models.ErrOperationNotFound):
+		return "not_found"
+	case errors.Is(err, models.ErrLimitExceeded):
+		return "limit_exceeded"
+	case errors.Is(err, models.ErrWalletFrozen), errors.Is(err, models.ErrWalletDebitBlock), errors.Is(err, models.ErrWalletClosed):
+		return "wallet_blocked"
+	case errors.Is(err, models.ErrInvalidAmount), errors.Is(err, models.ErrInvalidCurrency),
+		errors.Is(err, models.ErrInvalidOperation), errors.Is(err, models.ErrSameWallet),
+		errors.Is(err, models.ErrBalanceOverflow), errors.Is(err, models.ErrCaptureExceeds),
+		errors.Is(err, models.ErrHoldNotActive), errors.Is(err, models.ErrInvalidHoldTTL),
+		errors.Is(err, models.ErrNotReversible), errors.Is(err, models.ErrReversalExceeds), errors.Is(err, models.ErrAlreadyReversed):
+		return "rejected"
+	}
+	return "error"
+}
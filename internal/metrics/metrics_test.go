@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/wallets/:WALLET_UUID", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.GET("/metrics", gin.WrapH(Handler()))
+
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/wallets/%d", i), nil))
+	}
+
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/wallets/:WALLET_UUID", "404")); got != 3 {
+		t.Errorf("requests counted per route template = %v, want 3", got)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(w.Body.String(), "wallet_http_request_duration_seconds_bucket") {
+		t.Error("metrics endpoint does not expose the latency histogram")
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "success"},
+		{fmt.Errorf("failed to update balance: %w", models.ErrInsufficientFunds), "insufficient_funds"},
+		{models.ErrWalletNotFound, "not_found"},
+		{models.ErrLimitExceeded, "limit_exceeded"},
+		{models.ErrWalletFrozen, "wallet_blocked"},
+		{fmt.Errorf("connection reset"), "error"},
+	}
+
+	for _, tt := range tests {
+		if got := Outcome(tt.err); got != tt.want {
+			t.Errorf("Outcome(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
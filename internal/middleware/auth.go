@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/models"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+
+	// AnonymousPrincipal is the principal ID given to callers when
+	// authentication is disabled.
+	AnonymousPrincipal = "anonymous"
+)
+
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.Principal, error)
+}
+
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*models.Principal, error)
+}
+
+// Authenticate resolves the caller from an X-API-Key header or an
+// Authorization: Bearer JWT and stores it in the request context. Requests
+// without valid credentials are rejected with 401. tokens may be nil when
+// JWT authentication is not configured.
+func Authenticate(keys APIKeyAuthenticator, tokens TokenVerifier, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		apiKey := c.GetHeader(APIKeyHeader)
+		bearer, hasBearer := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+		var principal *models.Principal
+		var err error
+		switch {
+		case apiKey != "":
+			principal, err = keys.AuthenticateAPIKey(ctx, apiKey)
+		case hasBearer && tokens != nil:
+			principal, err = tokens.Verify(ctx, strings.TrimSpace(bearer))
+		default:
+			err = models.ErrUnauthenticated
+		}
+
+		if err != nil {
+			if !errors.Is(err, models.ErrUnauthenticated) {
+				logger.ErrorContext(ctx, "failed to authenticate request", slog.Any("error", err))
+				c.AbortWithStatusJSON(500, gin.H{"code": "INTERNAL_ERROR", "error": "internal server error"})
+				return
+			}
+			c.AbortWithStatusJSON(401, gin.H{"code": "UNAUTHENTICATED", "error": models.ErrUnauthenticated.Error()})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(ctx, principal))
+		c.Next()
+	}
+}
+
+// AllowAnonymous treats every caller as an admin. It is used when
+// authentication is disabled, e.g. in local development.
+func AllowAnonymous() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := &models.Principal{ID: AnonymousPrincipal, Role: models.RoleAdmin}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+func RequireRole(role models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.PrincipalFromContext(c.Request.Context())
+		if principal == nil {
+			c.AbortWithStatusJSON(401, gin.H{"code": "UNAUTHENTICATED", "error": models.ErrUnauthenticated.Error()})
+			return
+		}
+		if principal.Role != role {
+			c.AbortWithStatusJSON(403, gin.H{"code": "FORBIDDEN", "error": models.ErrForbidden.Error()})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/logging"
+	"github.com/itk/wallet/internal/models"
+)
+
+type staticKeys map[string]*models.Principal
+
+func (k staticKeys) AuthenticateAPIKey(ctx context.Context, key string) (*models.Principal, error) {
+	if principal, ok := k[key]; ok {
+		return principal, nil
+	}
+	return nil, models.ErrUnauthenticated
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := staticKeys{
+		"client-key": {ID: "merchant-1", Role: models.RoleClient},
+		"admin-key":  {ID: "ops", Role: models.RoleAdmin},
+	}
+
+	router := gin.New()
+	api := router.Group("/api/v1", Authenticate(keys, nil, logging.Discard()))
+	api.GET("/me", func(c *gin.Context) {
+		c.String(http.StatusOK, auth.PrincipalFromContext(c.Request.Context()).ID)
+	})
+	api.GET("/admin/limits", RequireRole(models.RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		path       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "no credentials", path: "/api/v1/me", wantStatus: 401},
+		{name: "unknown key", path: "/api/v1/me", headers: map[string]string{APIKeyHeader: "nope"}, wantStatus: 401},
+		{name: "bearer without JWT configured", path: "/api/v1/me", headers: map[string]string{"Authorization": "Bearer abc"}, wantStatus: 401},
+		{name: "valid key", path: "/api/v1/me", headers: map[string]string{APIKeyHeader: "client-key"}, wantStatus: 200, wantBody: "merchant-1"},
+		{name: "client on admin route", path: "/api/v1/admin/limits", headers: map[string]string{APIKeyHeader: "client-key"}, wantStatus: 403},
+		{name: "admin on admin route", path: "/api/v1/admin/limits", headers: map[string]string{APIKeyHeader: "admin-key"}, wantStatus: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
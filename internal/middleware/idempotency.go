@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/repository"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response for requests that repeat an Idempotency-Key.
+// Keys belong to the authenticated principal: the replay happens before the handler
+// authorizes anything, so a shared key must never reach another caller's response.
+// Responses with a 5xx status are not stored, so the client may retry them,
+// unless the request already took effect (see repository.WithIdempotencyKey).
+func Idempotency(store repository.IdempotencyInterface, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(400, gin.H{"code": "INVALID_IDEMPOTENCY_KEY", "error": "idempotency key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"code": "INVALID_REQUEST", "error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		requestHash := fingerprint(c.Request.Method, c.FullPath(), body)
+
+		var principalID string
+		if principal := auth.PrincipalFromContext(c.Request.Context()); principal != nil {
+			principalID = principal.ID
+		}
+
+		record, created, err := store.Reserve(c.Request.Context(), principalID, key, requestHash)
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "failed to reserve idempotency key", slog.Any("error", err))
+			c.AbortWithStatusJSON(500, gin.H{"code": "INTERNAL_ERROR", "error": "internal server error"})
+			return
+		}
+
+		if !created {
+			if record.RequestHash != requestHash {
+				c.AbortWithStatusJSON(409, gin.H{"code": "IDEMPOTENCY_KEY_REUSED", "error": "idempotency key was already used with a different request"})
+				return
+			}
+			if !record.Completed() {
+				c.AbortWithStatusJSON(409, gin.H{"code": "IDEMPOTENCY_KEY_IN_PROGRESS", "error": "request with this idempotency key is still in progress"})
+				return
+			}
+
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.ResponseStatus, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		ctx := context.WithoutCancel(c.Request.Context())
+		answered := false
+		defer func() {
+			if answered {
+				return
+			}
+			if err := store.Release(ctx, record); err != nil {
+				logger.ErrorContext(ctx, "failed to release idempotency key", slog.Any("error", err))
+			}
+		}()
+
+		c.Request = c.Request.WithContext(repository.WithIdempotencyKey(c.Request.Context(), record))
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= 500 {
+			return
+		}
+
+		// The client has its answer, so the key must not be released even if
+		// storing the response fails: a retry would repeat the operation.
+		answered = true
+		if err := store.Complete(ctx, record, status, recorder.body.Bytes()); err != nil {
+			logger.ErrorContext(ctx, "failed to store idempotent response", slog.Any("error", err))
+		}
+	}
+}
+
+func fingerprint(method, path string, body []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err == nil {
+		body = compact.Bytes()
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{' '})
+	h.Write([]byte(path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
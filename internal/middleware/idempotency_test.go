@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/logging"
+	"github.com/itk/wallet/internal/models"
+)
+
+type memoryIdempotencyStore struct {
+	mu           sync.Mutex
+	records      map[string]*models.IdempotencyRecord
+	failComplete bool
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, principalID, key string, requestHash string) (*models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := principalID + "\x00" + key
+	if record, ok := s.records[id]; ok {
+		copied := *record
+		return &copied, false, nil
+	}
+
+	s.records[id] = &models.IdempotencyRecord{PrincipalID: principalID, Key: key, RequestHash: requestHash, Reservation: uuid.New()}
+	copied := *s.records[id]
+	return &copied, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, record *models.IdempotencyRecord, status int, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failComplete {
+		return errors.New("connection reset")
+	}
+	now := time.Now()
+	stored := s.records[record.PrincipalID+"\x00"+record.Key]
+	stored.ResponseStatus = status
+	stored.ResponseBody = append([]byte(nil), body...)
+	stored.CompletedAt = &now
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := record.PrincipalID + "\x00" + record.Key
+	if stored, ok := s.records[id]; ok && stored.Reservation == record.Reservation && !stored.Completed() {
+		delete(s.records, id)
+	}
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	status := http.StatusOK
+	router := gin.New()
+	router.POST("/wallet", Idempotency(newMemoryIdempotencyStore(), logging.Discard()), func(c *gin.Context) {
+		calls++
+		c.JSON(status, gin.H{"call": calls})
+	})
+
+	do := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallet", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do("key-1", `{"amount": 100}`)
+	if first.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("first request: got status %d after %d calls", first.Code, calls)
+	}
+
+	replay := do("key-1", `{"amount":100}`)
+	if replay.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("replay: got status %d after %d calls", replay.Code, calls)
+	}
+	if replay.Body.String() != first.Body.String() {
+		t.Errorf("replay body %q, want %q", replay.Body.String(), first.Body.String())
+	}
+	if replay.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("replay should be marked with %s header", IdempotentReplayedHeader)
+	}
+
+	if conflict := do("key-1", `{"amount": 200}`); conflict.Code != http.StatusConflict {
+		t.Errorf("different body: got status %d, want %d", conflict.Code, http.StatusConflict)
+	}
+
+	status = http.StatusInternalServerError
+	do("key-2", `{"amount": 100}`)
+	status = http.StatusOK
+	if retry := do("key-2", `{"amount": 100}`); retry.Code != http.StatusOK || calls != 3 {
+		t.Errorf("retry after server error: got status %d after %d calls", retry.Code, calls)
+	}
+
+	do("", `{"amount": 100}`)
+	do("", `{"amount": 100}`)
+	if calls != 5 {
+		t.Errorf("requests without key should not be deduplicated, got %d calls", calls)
+	}
+}
+
+func TestIdempotency_ScopedToPrincipal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		principal := &models.Principal{ID: c.GetHeader("X-Principal"), Role: models.RoleClient}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	})
+	router.POST("/wallet", Idempotency(newMemoryIdempotencyStore(), logging.Discard()), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"principal": c.GetHeader("X-Principal"), "call": calls})
+	})
+
+	do := func(principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallet", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(IdempotencyKeyHeader, "shared-key")
+		req.Header.Set("X-Principal", principal)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do("merchant-1")
+	second := do("merchant-2")
+	if second.Code != http.StatusOK || calls != 2 {
+		t.Fatalf("second principal: got status %d after %d calls, want a fresh request", second.Code, calls)
+	}
+	if second.Header().Get(IdempotentReplayedHeader) != "" || second.Body.String() == first.Body.String() {
+		t.Errorf("second principal was served the first principal's response %q", second.Body.String())
+	}
+
+	if replay := do("merchant-1"); replay.Body.String() != first.Body.String() || calls != 2 {
+		t.Errorf("same principal: got %q after %d calls, want replay of %q", replay.Body.String(), calls, first.Body.String())
+	}
+}
+
+func TestIdempotency_KeepsKeyWhenResponseIsLost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	store := newMemoryIdempotencyStore()
+	store.failComplete = true
+	router := gin.New()
+	router.POST("/wallet", Idempotency(store, logging.Discard()), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallet", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if first := do(); first.Code != http.StatusOK {
+		t.Fatalf("first request: got status %d", first.Code)
+	}
+	if retry := do(); retry.Code != http.StatusConflict || calls != 1 {
+		t.Errorf("retry after a lost response: got status %d after %d calls, want 409 after 1", retry.Code, calls)
+	}
+}
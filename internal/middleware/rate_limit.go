@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/metrics"
+	"github.com/itk/wallet/internal/ratelimit"
+)
+
+// maxRateLimitBodyBytes caps the body the limiter reads to find wallets. It
+// fits a batch of models.MaxBatchSize operations with room to spare.
+const maxRateLimitBodyBytes = 1 << 20
+
+// walletRequest picks the wallets a request debits or credits out of the
+// bodies of the operation, transfer and batch endpoints.
+type walletRequest struct {
+	WalletID     *uuid.UUID      `json:"valletId"`
+	FromWalletID *uuid.UUID      `json:"fromWalletId"`
+	Operations   []walletRequest `json:"operations"`
+}
+
+// RateLimit limits requests per client and per wallet with token buckets in
+// store. The client is the authenticated principal, or the remote address
+// for anonymous callers. The wallet comes from the WALLET_UUID path parameter
+// or the request body; a batch takes a token from every distinct wallet it
+// touches. Wallet buckets are kept per client, because the wallet is named
+// by the request before anyone has checked the caller may use it: a shared
+// bucket would let any client drain the owner's budget. Rejected requests get 429 with a Retry-After header, and bodies
+// over maxRateLimitBodyBytes get 413.
+// If the store fails the request is let through, so a limiter outage does
+// not take the API down with it.
+func RateLimit(store ratelimit.Store, cfg ratelimit.Config, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := clientKey(c)
+		if cfg.Client.Enabled() && !allow(c, store, "client", "client:"+client, cfg.Client, logger) {
+			return
+		}
+
+		if cfg.Wallet.Enabled() {
+			walletIDs, err := requestWalletIDs(c)
+			if err != nil {
+				c.AbortWithStatusJSON(413, gin.H{"code": "REQUEST_TOO_LARGE", "error": err.Error()})
+				return
+			}
+			for _, walletID := range walletIDs {
+				if !allow(c, store, "wallet", "wallet:"+client+":"+walletID.String(), cfg.Wallet, logger) {
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func allow(c *gin.Context, store ratelimit.Store, scope, key string, limit ratelimit.Limit, logger *slog.Logger) bool {
+	decision, err := store.Take(c.Request.Context(), key, limit)
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "failed to check rate limit", slog.String("scope", scope), slog.Any("error", err))
+		return true
+	}
+	if decision.Allowed {
+		return true
+	}
+
+	metrics.RateLimited.WithLabelValues(scope).Inc()
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(max(decision.RetryAfter, time.Second).Seconds()))))
+	c.AbortWithStatusJSON(429, gin.H{"code": "RATE_LIMITED", "error": "rate limit exceeded"})
+	return false
+}
+
+func clientKey(c *gin.Context) string {
+	principal := auth.PrincipalFromContext(c.Request.Context())
+	if principal == nil || principal.ID == AnonymousPrincipal {
+		return "ip:" + c.ClientIP()
+	}
+
+	return principal.ID
+}
+
+// requestWalletIDs returns the distinct wallets of the request. It only
+// fails when the body is too large to inspect.
+func requestWalletIDs(c *gin.Context) ([]uuid.UUID, error) {
+	if param := c.Param("WALLET_UUID"); param != "" {
+		walletID, err := uuid.Parse(param)
+		if err != nil {
+			return nil, nil
+		}
+		return []uuid.UUID{walletID}, nil
+	}
+
+	if c.Request.Body == nil || c.ContentType() != gin.MIMEJSON {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxRateLimitBodyBytes))
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit)
+		}
+		return nil, nil
+	}
+
+	var req walletRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, nil
+	}
+
+	var walletIDs []uuid.UUID
+	for _, r := range append([]walletRequest{req}, req.Operations...) {
+		switch {
+		case r.WalletID != nil:
+			walletIDs = append(walletIDs, *r.WalletID)
+		case r.FromWalletID != nil:
+			walletIDs = append(walletIDs, *r.FromWalletID)
+		}
+	}
+	slices.SortFunc(walletIDs, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+	return slices.Compact(walletIDs), nil
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/logging"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := ratelimit.Config{
+		Client: ratelimit.Limit{Rate: 0.001, Burst: 4},
+		Wallet: ratelimit.Limit{Rate: 0.001, Burst: 1},
+	}
+
+	router := gin.New()
+	router.Use(AllowAnonymous(), RateLimit(ratelimit.NewMemoryStore(), cfg, logging.Discard()))
+	router.POST("/wallet", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/wallets/:WALLET_UUID", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	const walletA = "/wallets/5f0c6a0e-7d3b-4a59-9a38-2a4f6f7f0c01"
+	const walletB = `{"valletId":"5f0c6a0e-7d3b-4a59-9a38-2a4f6f7f0c02","amount":1}`
+
+	if w := send(http.MethodGet, walletA, ""); w.Code != 200 {
+		t.Fatalf("first request status = %d, want 200", w.Code)
+	}
+
+	w := send(http.MethodGet, walletA, "")
+	if w.Code != 429 {
+		t.Fatalf("second request for the same wallet status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 response has no Retry-After header")
+	}
+
+	if w := send(http.MethodPost, "/wallet", walletB); w.Code != 200 {
+		t.Errorf("request for another wallet status = %d, want 200", w.Code)
+	}
+	if w := send(http.MethodPost, "/wallet", walletB); w.Code != 429 {
+		t.Errorf("repeated body wallet status = %d, want 429", w.Code)
+	}
+
+	if w := send(http.MethodPost, "/wallet", "{}"); w.Code != 429 {
+		t.Errorf("request above the client burst status = %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimit_Batch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := ratelimit.Config{Wallet: ratelimit.Limit{Rate: 0.001, Burst: 1}}
+
+	router := gin.New()
+	router.Use(AllowAnonymous(), RateLimit(ratelimit.NewMemoryStore(), cfg, logging.Discard()))
+	router.POST("/wallet/batch", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallet/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	const walletA = `{"valletId":"5f0c6a0e-7d3b-4a59-9a38-2a4f6f7f0c01","amount":1}`
+	const walletB = `{"valletId":"5f0c6a0e-7d3b-4a59-9a38-2a4f6f7f0c02","amount":1}`
+
+	if w := send(`{"operations":[` + walletA + "," + walletA + "," + walletB + `]}`); w.Code != 200 {
+		t.Fatalf("first batch status = %d, want 200 with one token per distinct wallet", w.Code)
+	}
+	if w := send(`{"operations":[` + walletB + `]}`); w.Code != 429 {
+		t.Errorf("batch for a drained wallet status = %d, want 429", w.Code)
+	}
+
+	large := `{"operations":[` + strings.Repeat(walletA+",", maxRateLimitBodyBytes/len(walletA)) + walletA + `]}`
+	if w := send(large); w.Code != 413 {
+		t.Errorf("oversized body status = %d, want 413", w.Code)
+	}
+}
+
+func TestRateLimit_WalletBucketsPerClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := ratelimit.Config{Wallet: ratelimit.Limit{Rate: 0.001, Burst: 1}}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		principal := &models.Principal{ID: c.GetHeader("X-Principal"), Role: models.RoleClient}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	}, RateLimit(ratelimit.NewMemoryStore(), cfg, logging.Discard()))
+	router.GET("/wallets/:WALLET_UUID", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(principal string) int {
+		req := httptest.NewRequest(http.MethodGet, "/wallets/5f0c6a0e-7d3b-4a59-9a38-2a4f6f7f0c01", nil)
+		req.Header.Set("X-Principal", principal)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send("attacker"); code != 200 {
+		t.Fatalf("first request status = %d, want 200", code)
+	}
+	if code := send("attacker"); code != 429 {
+		t.Errorf("repeated request status = %d, want 429", code)
+	}
+	if code := send("owner"); code != 200 {
+		t.Errorf("owner status = %d after another client drained its own bucket, want 200", code)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	logger := logging.New(&logs, slog.LevelInfo)
+
+	router := gin.New()
+	router.Use(RequestID(), AccessLog(logger))
+	router.GET("/wallets/:WALLET_UUID", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	t.Run("echoes client request ID", func(t *testing.T) {
+		logs.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/wallets/1", nil)
+		req.Header.Set(RequestIDHeader, "client-req-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get(RequestIDHeader); got != "client-req-1" {
+			t.Errorf("%s = %q, want client-req-1", RequestIDHeader, got)
+		}
+
+		var line map[string]any
+		if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+			t.Fatalf("access log is not JSON: %v: %s", err, logs.String())
+		}
+		if line["request_id"] != "client-req-1" || line["route"] != "/wallets/:WALLET_UUID" {
+			t.Errorf("unexpected access log line: %v", line)
+		}
+	})
+
+	t.Run("generates request ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/wallets/1", nil))
+
+		if _, err := uuid.Parse(w.Header().Get(RequestIDHeader)); err != nil {
+			t.Errorf("expected a generated UUID request ID, got %q", w.Header().Get(RequestIDHeader))
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Role string
+
+const (
+	RoleClient Role = "CLIENT"
+	RoleAdmin  Role = "ADMIN"
+)
+
+func (r Role) IsValid() bool {
+	return r == RoleClient || r == RoleAdmin
+}
+
+// Principal is the authenticated caller. WalletIDs lists wallets granted by
+// the credential itself, e.g. a JWT claim; ownership recorded in the
+// database is checked separately.
+type Principal struct {
+	ID        string
+	Role      Role
+	WalletIDs []uuid.UUID
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+func (p *Principal) HasWalletGrant(walletID uuid.UUID) bool {
+	return slices.Contains(p.WalletIDs, walletID)
+}
+
+type APIKey struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Principal string     `json:"principal" db:"principal"`
+	Role      Role       `json:"role" db:"role"`
+	Key       string     `json:"key,omitempty"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
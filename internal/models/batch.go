@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const MaxBatchSize = 1000
+
+type BatchMode string
+
+const (
+	// BatchModeAtomic applies every item or none of them.
+	BatchModeAtomic BatchMode = "ATOMIC"
+	// BatchModeBestEffort applies each item that succeeds and reports the
+	// outcome of every item.
+	BatchModeBestEffort BatchMode = "BEST_EFFORT"
+)
+
+func (m BatchMode) IsValid() bool {
+	return m == BatchModeAtomic || m == BatchModeBestEffort
+}
+
+// BatchItem is one deposit or withdrawal of a batch. Index is its position
+// in the request.
+type BatchItem struct {
+	Index     int
+	WalletID  uuid.UUID
+	Operation OperationType
+	Currency  Currency
+	Amount    int64
+}
+
+// BatchResult is the outcome of one batch item: the recorded operation on
+// success, Err otherwise.
+type BatchResult struct {
+	Index     int
+	Operation *WalletOperation
+	Err       error
+}
+
+// BatchItemError is returned when an item fails an atomic batch.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,48 @@
+package models
+
+import "errors"
+
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrWalletExists        = errors.New("wallet already exists")
+	ErrWalletFrozen        = errors.New("wallet is frozen")
+	ErrWalletDebitBlock    = errors.New("wallet is blocked for debits")
+	ErrWalletClosed        = errors.New("wallet is closed")
+	ErrWalletNotEmpty      = errors.New("wallet balance must be zero to close it")
+	ErrInvalidStatus       = errors.New("invalid wallet status")
+	ErrUnauthenticated     = errors.New("authentication required")
+	ErrForbidden           = errors.New("access to this resource is forbidden")
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrInvalidPrincipal    = errors.New("invalid principal")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrBalanceOverflow     = errors.New("balance would exceed the maximum allowed value")
+	ErrInvalidWalletID     = errors.New("wallet ID is required")
+	ErrInvalidOperation    = errors.New("invalid operation type")
+	ErrInvalidAmount       = errors.New("amount must be greater than zero")
+	ErrInvalidCurrency     = errors.New("unknown currency")
+	ErrSameWallet          = errors.New("cannot transfer to the same wallet")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrInvalidTimeRange    = errors.New("invalid time range: from must be before to")
+	ErrFutureBalanceTime   = errors.New("balance time must not be in the future")
+	ErrHoldNotFound        = errors.New("hold not found")
+	ErrHoldNotActive       = errors.New("hold is not active")
+	ErrCaptureExceeds      = errors.New("capture amount exceeds held amount")
+	ErrInvalidHoldTTL      = errors.New("invalid hold TTL")
+	ErrLimitExceeded       = errors.New("withdrawal limit exceeded")
+	ErrInvalidLimits       = errors.New("limits must be greater than zero")
+	ErrLimitsNotFound      = errors.New("withdrawal limits not found")
+	ErrInvalidBatchMode    = errors.New("batch mode must be ATOMIC or BEST_EFFORT")
+	ErrInvalidBatchSize    = errors.New("batch must contain between 1 and 1000 operations")
+	ErrWebhookNotFound     = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL   = errors.New("webhook url must be an absolute http or https url")
+	ErrWebhookURLNotPublic = errors.New("webhook url must point to a public address")
+	ErrInvalidEventType    = errors.New("unknown event type")
+	ErrReconcileRunning    = errors.New("another reconciliation run is in progress")
+	ErrOperationNotFound   = errors.New("operation not found")
+	ErrNotReversible       = errors.New("operation cannot be reversed")
+	ErrReversalExceeds     = errors.New("reversal amount exceeds the amount left to reverse")
+	ErrAlreadyReversed     = errors.New("operation is already fully reversed")
+	ErrIdempotencyKeyLost  = errors.New("idempotency key reservation was taken over by another request")
+)
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a balance change recorded in the outbox and published to
+// downstream consumers. Seq orders the events of a wallet.
+type Event struct {
+	ID        uuid.UUID       `json:"id"`
+	Seq       int64           `json:"seq"`
+	WalletID  uuid.UUID       `json:"walletId"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Attempts  int             `json:"-"`
+}
+
+// OperationEventType names the event emitted for a ledger operation, e.g.
+// wallet.deposit or wallet.transfer_out.
+func OperationEventType(operation OperationType) string {
+	return "wallet." + strings.ToLower(string(operation))
+}
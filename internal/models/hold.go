@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "ACTIVE"
+	HoldStatusCaptured HoldStatus = "CAPTURED"
+	HoldStatusReleased HoldStatus = "RELEASED"
+	HoldStatusExpired  HoldStatus = "EXPIRED"
+)
+
+type Hold struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	WalletID       uuid.UUID  `json:"walletId" db:"wallet_id"`
+	Currency       Currency   `json:"currency" db:"currency"`
+	Amount         int64      `json:"amount" db:"amount"`
+	CapturedAmount int64      `json:"capturedAmount" db:"captured_amount"`
+	Status         HoldStatus `json:"status" db:"status"`
+	ExpiresAt      time.Time  `json:"expiresAt" db:"expires_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+}
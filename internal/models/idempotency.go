@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IdempotencyRecord struct {
+	PrincipalID    string     `db:"principal_id"`
+	Key            string     `db:"key"`
+	RequestHash    string     `db:"request_hash"`
+	Reservation    uuid.UUID  `db:"reservation"`
+	ResponseStatus int        `db:"response_status"`
+	ResponseBody   []byte     `db:"response_body"`
+	CreatedAt      time.Time  `db:"created_at"`
+	ReservedAt     time.Time  `db:"reserved_at"`
+	AppliedAt      *time.Time `db:"applied_at"`
+	CompletedAt    *time.Time `db:"completed_at"`
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.CompletedAt != nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutflowOperationTypes are the operations counted against withdrawal limits.
+var OutflowOperationTypes = []OperationType{
+	OperationTypeWithdraw,
+	OperationTypeTransferOut,
+	OperationTypeCapture,
+}
+
+// WithdrawalLimits is a limit policy for one currency. A nil WalletID marks the
+// default policy; nil limits are not enforced.
+type WithdrawalLimits struct {
+	WalletID  *uuid.UUID `json:"walletId,omitempty" db:"wallet_id"`
+	Currency  Currency   `json:"currency" db:"currency"`
+	MaxSingle *int64     `json:"maxSingle" db:"max_single"`
+	Daily     *int64     `json:"daily" db:"daily"`
+	Monthly   *int64     `json:"monthly" db:"monthly"`
+	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+func (l *WithdrawalLimits) Validate() error {
+	for _, limit := range []*int64{l.MaxSingle, l.Daily, l.Monthly} {
+		if limit != nil && *limit <= 0 {
+			return ErrInvalidLimits
+		}
+	}
+
+	return nil
+}
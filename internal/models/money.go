@@ -0,0 +1,16 @@
+package models
+
+import "math"
+
+// AddAmount credits amount to balance, failing instead of wrapping around
+// when the result does not fit into int64 minor units.
+func AddAmount(balance, amount int64) (int64, error) {
+	if amount > 0 && balance > math.MaxInt64-amount {
+		return 0, ErrBalanceOverflow
+	}
+	if amount < 0 && balance < math.MinInt64-amount {
+		return 0, ErrBalanceOverflow
+	}
+
+	return balance + amount, nil
+}
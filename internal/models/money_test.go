@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAddAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance int64
+		amount  int64
+		want    int64
+		wantErr error
+	}{
+		{name: "regular deposit", balance: 100, amount: 50, want: 150},
+		{name: "above int32 range", balance: math.MaxInt32, amount: 1, want: math.MaxInt32 + 1},
+		{name: "up to max", balance: math.MaxInt64 - 10, amount: 10, want: math.MaxInt64},
+		{name: "overflow", balance: math.MaxInt64 - 10, amount: 11, wantErr: ErrBalanceOverflow},
+		{name: "negative adjustment", balance: 100, amount: -30, want: 70},
+		{name: "underflow", balance: math.MinInt64 + 5, amount: -6, wantErr: ErrBalanceOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AddAmount(tt.balance, tt.amount)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DebitOperationTypes are the operations that decrease a balance. A reversal
+// moves the balance opposite to the operation it reverses, and every other
+// operation type increases it.
+var DebitOperationTypes = []OperationType{
+	OperationTypeWithdraw,
+	OperationTypeTransferOut,
+	OperationTypeCapture,
+}
+
+// BalanceMismatch is a stored balance that differs from the sum of the
+// ledger entries of the wallet in that currency.
+type BalanceMismatch struct {
+	WalletID      uuid.UUID `json:"walletId"`
+	Currency      Currency  `json:"currency"`
+	StoredBalance int64     `json:"storedBalance"`
+	LedgerBalance int64     `json:"ledgerBalance"`
+	Difference    int64     `json:"difference"`
+	Repaired      bool      `json:"repaired"`
+	RepairError   string    `json:"repairError,omitempty"`
+}
+
+// ReconciliationReport is the outcome of one reconciliation run.
+type ReconciliationReport struct {
+	ID              uuid.UUID         `json:"id"`
+	StartedAt       time.Time         `json:"startedAt"`
+	FinishedAt      time.Time         `json:"finishedAt"`
+	BalancesChecked int               `json:"balancesChecked"`
+	Mismatches      []BalanceMismatch `json:"mismatches"`
+	Repaired        int               `json:"repaired"`
+}
+
+// BalanceAdjustment audits a stored balance reset to the ledger balance by a
+// reconciliation run.
+type BalanceAdjustment struct {
+	ID            uuid.UUID `json:"id"`
+	RunID         uuid.UUID `json:"runId"`
+	WalletID      uuid.UUID `json:"walletId"`
+	Currency      Currency  `json:"currency"`
+	BalanceBefore int64     `json:"balanceBefore"`
+	BalanceAfter  int64     `json:"balanceAfter"`
+	Actor         string    `json:"actor"`
+	Reason        string    `json:"reason"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WalletStatus string
+
+const (
+	WalletStatusActive       WalletStatus = "ACTIVE"
+	WalletStatusFrozen       WalletStatus = "FROZEN"
+	WalletStatusDebitBlocked WalletStatus = "DEBIT_BLOCKED"
+	WalletStatusClosed       WalletStatus = "CLOSED"
+)
+
+func (s WalletStatus) IsValid() bool {
+	switch s {
+	case WalletStatusActive, WalletStatusFrozen, WalletStatusDebitBlocked, WalletStatusClosed:
+		return true
+	}
+	return false
+}
+
+// CheckCredit reports whether money may be added to a wallet in this status.
+func (s WalletStatus) CheckCredit() error {
+	switch s {
+	case WalletStatusFrozen:
+		return ErrWalletFrozen
+	case WalletStatusClosed:
+		return ErrWalletClosed
+	}
+	return nil
+}
+
+// CheckDebit reports whether money may be taken or reserved from a wallet in this status.
+func (s WalletStatus) CheckDebit() error {
+	switch s {
+	case WalletStatusFrozen:
+		return ErrWalletFrozen
+	case WalletStatusDebitBlocked:
+		return ErrWalletDebitBlock
+	case WalletStatusClosed:
+		return ErrWalletClosed
+	}
+	return nil
+}
+
+type WalletStatusChange struct {
+	ID         int64        `json:"id" db:"id"`
+	WalletID   uuid.UUID    `json:"walletId" db:"wallet_id"`
+	FromStatus WalletStatus `json:"fromStatus" db:"from_status"`
+	ToStatus   WalletStatus `json:"toStatus" db:"to_status"`
+	Reason     string       `json:"reason" db:"reason"`
+	Actor      string       `json:"actor" db:"actor"`
+	CreatedAt  time.Time    `json:"createdAt" db:"created_at"`
+}
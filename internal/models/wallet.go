@@ -1,28 +1,100 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type WalletOperation struct {
-	WalletID  uuid.UUID     `json:"valletId"`
-	Operation OperationType `json:"OperationType"`
-	Amount    int           `json:"amount"`
-	CreatedAt time.Time     `json:"createdAt"`
+	ID            uuid.UUID     `json:"id" db:"id"`
+	Seq           int64         `json:"-" db:"seq"`
+	WalletID      uuid.UUID     `json:"valletId" db:"wallet_id"`
+	Operation     OperationType `json:"operationType" db:"operation_type"`
+	Currency      Currency      `json:"currency" db:"currency"`
+	Amount        int64         `json:"amount" db:"amount"`
+	BalanceBefore int64         `json:"balanceBefore" db:"balance_before"`
+	BalanceAfter  int64         `json:"balanceAfter" db:"balance_after"`
+	TransferID    *uuid.UUID    `json:"transferId,omitempty" db:"transfer_id"`
+	HoldID        *uuid.UUID    `json:"holdId,omitempty" db:"hold_id"`
+	ReversalOf    *uuid.UUID    `json:"reversalOf,omitempty" db:"reversal_of"`
+	CreatedAt     time.Time     `json:"createdAt" db:"created_at"`
 }
 
 type OperationType string
 
 const (
-	OperationTypeWithdraw OperationType = "WITHDRAW"
-	OperationTypeDeposit  OperationType = "DEPOSIT"
+	OperationTypeWithdraw       OperationType = "WITHDRAW"
+	OperationTypeDeposit        OperationType = "DEPOSIT"
+	OperationTypeOpeningBalance OperationType = "OPENING_BALANCE"
+	OperationTypeTransferOut    OperationType = "TRANSFER_OUT"
+	OperationTypeTransferIn     OperationType = "TRANSFER_IN"
+	OperationTypeCapture        OperationType = "CAPTURE"
+	OperationTypeReversal       OperationType = "REVERSAL"
 )
 
+func (t OperationType) IsValid() bool {
+	switch t {
+	case OperationTypeDeposit, OperationTypeWithdraw, OperationTypeOpeningBalance,
+		OperationTypeTransferOut, OperationTypeTransferIn, OperationTypeCapture, OperationTypeReversal:
+		return true
+	}
+	return false
+}
+
+func (t OperationType) IsDebit() bool {
+	return slices.Contains(DebitOperationTypes, t)
+}
+
+// IsReversible reports whether an operation of this type may be reversed.
+// Transfers are excluded because reversing one leg would leave the other
+// wallet untouched.
+func (t OperationType) IsReversible() bool {
+	switch t {
+	case OperationTypeDeposit, OperationTypeWithdraw, OperationTypeCapture:
+		return true
+	}
+	return false
+}
+
+type OperationFilter struct {
+	Types     []OperationType
+	Currency  Currency
+	From      *time.Time
+	To        *time.Time
+	BeforeSeq int64
+	Limit     int
+}
+
 type Wallet struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Balance   int       `json:"balance" db:"balance"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	ID        uuid.UUID    `json:"id" db:"id"`
+	Status    WalletStatus `json:"status" db:"status"`
+	Balances  []Balance    `json:"balances"`
+	CreatedAt time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time    `json:"updatedAt" db:"updated_at"`
+}
+
+type Balance struct {
+	Currency  Currency  `json:"currency" db:"currency"`
+	Balance   int64     `json:"balance" db:"balance"`
+	Available int64     `json:"available" db:"available"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
+
+// BalanceAt is the balance of a wallet in one currency at an instant.
+type BalanceAt struct {
+	WalletID uuid.UUID `json:"walletId"`
+	Currency Currency  `json:"currency"`
+	Balance  int64     `json:"balance"`
+	At       time.Time `json:"at"`
+}
+
+type Transfer struct {
+	ID           uuid.UUID `json:"id"`
+	FromWalletID uuid.UUID `json:"fromWalletId"`
+	ToWalletID   uuid.UUID `json:"toWalletId"`
+	Currency     Currency  `json:"currency"`
+	Amount       int64     `json:"amount"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscription pushes the events of a principal's wallets to a
+// merchant URL. An empty EventTypes receives every event type. Secret is
+// only returned when the subscription is created or the secret rotated.
+type WebhookSubscription struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	Principal  string    `json:"principal" db:"principal"`
+	AllWallets bool      `json:"allWallets" db:"all_wallets"`
+	URL        string    `json:"url" db:"url"`
+	EventTypes []string  `json:"eventTypes" db:"event_types"`
+	Secret     string    `json:"secret,omitempty" db:"secret"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "PENDING"
+	DeliveryStatusDelivered DeliveryStatus = "DELIVERED"
+	// DeliveryStatusDead marks a delivery that ran out of attempts.
+	DeliveryStatusDead DeliveryStatus = "DEAD"
+)
+
+func (s DeliveryStatus) IsValid() bool {
+	return s == DeliveryStatusPending || s == DeliveryStatusDelivered || s == DeliveryStatusDead
+}
+
+// WebhookDelivery is one event queued for one subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	SubscriptionID uuid.UUID       `json:"subscriptionId" db:"subscription_id"`
+	EventID        uuid.UUID       `json:"eventId" db:"event_id"`
+	EventType      string          `json:"eventType" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         DeliveryStatus  `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastStatusCode *int            `json:"lastStatusCode,omitempty" db:"last_status_code"`
+	LastError      *string         `json:"lastError,omitempty" db:"last_error"`
+	CreatedAt      time.Time       `json:"createdAt" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty" db:"delivered_at"`
+}
+
+// IsValidEventType reports whether eventType names an operation event, e.g.
+// wallet.deposit.
+func IsValidEventType(eventType string) bool {
+	operation, ok := strings.CutPrefix(eventType, "wallet.")
+	return ok && operation == strings.ToLower(operation) && OperationType(strings.ToUpper(operation)).IsValid()
+}
+
+// PendingDelivery is a delivery claimed for sending together with the
+// subscription's target and signing secret.
+type PendingDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// DeliveryAttempt is the outcome of sending a delivery. StatusCode is zero
+// when no response was received. A failed attempt is retried after
+// RetryAfter unless Dead is set.
+type DeliveryAttempt struct {
+	Delivered  bool
+	StatusCode int
+	Error      string
+	Dead       bool
+	RetryAfter time.Duration
+}
@@ -0,0 +1,127 @@
+// Package outbox relays balance-change events recorded in the transactional
+// outbox to downstream consumers.
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/itk/wallet/internal/metrics"
+	"github.com/itk/wallet/internal/models"
+)
+
+const (
+	SinkNone    = "none"
+	SinkWebhook = "webhook"
+	SinkKafka   = "kafka"
+	SinkFile    = "file"
+)
+
+type Config struct {
+	Sink           string
+	WebhookURL     string
+	KafkaBrokers   string
+	KafkaTopic     string
+	File           string
+	PollInterval   time.Duration
+	BatchSize      int
+	PublishTimeout time.Duration
+	MaxBackoff     time.Duration
+	Retention      time.Duration
+}
+
+// Sink delivers events. Publish returns only after the event is accepted,
+// so a nil error means the event may be marked published.
+type Sink interface {
+	Publish(ctx context.Context, event models.Event) error
+	Close() error
+}
+
+type Store interface {
+	RelayPending(ctx context.Context, limit int, publish func(context.Context, models.Event) error, backoff func(attempts int) time.Duration) (published, failed int, err error)
+}
+
+// Relay polls the outbox and publishes pending events to a sink. Delivery
+// is at least once: consumers should deduplicate by event ID.
+type Relay struct {
+	store   Store
+	sink    Sink
+	cfg     Config
+	backoff func(attempts int) time.Duration
+	logger  *slog.Logger
+}
+
+func NewRelay(store Store, sink Sink, cfg Config, logger *slog.Logger) *Relay {
+	return &Relay{
+		store:   store,
+		sink:    sink,
+		cfg:     cfg,
+		backoff: Backoff(cfg.PollInterval, cfg.MaxBackoff),
+		logger:  logger,
+	}
+}
+
+// Run relays events until ctx is cancelled. A full batch is followed
+// immediately by the next one so a backlog drains without waiting.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		published, failed, err := r.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "failed to relay outbox events", slog.Any("error", err))
+		}
+		if published > 0 || failed > 0 {
+			r.logger.DebugContext(ctx, "outbox events relayed", slog.Int("published", published), slog.Int("failed", failed))
+		}
+		if err == nil && published+failed >= r.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) RunOnce(ctx context.Context) (int, int, error) {
+	published, failed, err := r.store.RelayPending(ctx, r.cfg.BatchSize, r.publish, r.backoff)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	metrics.OutboxEvents.WithLabelValues("published").Add(float64(published))
+	metrics.OutboxEvents.WithLabelValues("failed").Add(float64(failed))
+
+	return published, failed, nil
+}
+
+func (r *Relay) publish(ctx context.Context, event models.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+	defer cancel()
+
+	if err := r.sink.Publish(ctx, event); err != nil {
+		r.logger.WarnContext(ctx, "failed to publish event",
+			slog.String("event_id", event.ID.String()), slog.String("wallet_id", event.WalletID.String()),
+			slog.Int("attempt", event.Attempts+1), slog.Any("error", err))
+		return err
+	}
+
+	return nil
+}
+
+// Backoff returns an exponential retry delay starting at base and doubling
+// per attempt up to limit.
+func Backoff(base, limit time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		delay := base
+		for i := 1; i < attempts && delay < limit; i++ {
+			delay *= 2
+		}
+		return min(delay, limit)
+	}
+}
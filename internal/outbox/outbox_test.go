@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+func TestBackoff(t *testing.T) {
+	backoff := Backoff(time.Second, 10*time.Second)
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, delay := range want {
+		if got := backoff(i + 1); got != delay {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, delay)
+		}
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	event := models.Event{ID: uuid.New(), WalletID: uuid.New(), Type: "wallet.deposit", Payload: json.RawMessage(`{"amount":100}`)}
+
+	status := http.StatusNoContent
+	var received models.Event
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, time.Second)
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+	if received.ID != event.ID || string(received.Payload) != `{"amount":100}` {
+		t.Errorf("received %+v, want %+v", received, event)
+	}
+	if header.Get(EventIDHeader) != event.ID.String() || header.Get(EventTypeHeader) != event.Type {
+		t.Errorf("event headers = %v", header)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := sink.Publish(context.Background(), event); err == nil {
+		t.Error("Publish() succeeded on a 503 response")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink() unexpected error: %v", err)
+	}
+
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	for _, id := range ids {
+		if err := sink.Publish(context.Background(), models.Event{ID: id, Payload: json.RawMessage(`{}`)}); err != nil {
+			t.Fatalf("Publish() unexpected error: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var got []uuid.UUID
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event models.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, event.ID)
+	}
+	if len(got) != 2 || got[0] != ids[0] || got[1] != ids[1] {
+		t.Errorf("file holds %v, want %v", got, ids)
+	}
+}
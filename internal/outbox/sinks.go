@@ -0,0 +1,176 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itk/wallet/internal/models"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	EventIDHeader   = "X-Event-ID"
+	EventTypeHeader = "X-Event-Type"
+)
+
+// NewSink builds the sink selected by cfg.Sink. It returns nil for SinkNone.
+func NewSink(cfg Config) (Sink, error) {
+	switch cfg.Sink {
+	case SinkNone:
+		return nil, nil
+	case SinkWebhook:
+		return NewWebhookSink(cfg.WebhookURL, cfg.PublishTimeout), nil
+	case SinkKafka:
+		return NewKafkaSink(strings.Split(cfg.KafkaBrokers, ","), cfg.KafkaTopic), nil
+	case SinkFile:
+		return NewFileSink(cfg.File)
+	default:
+		return nil, fmt.Errorf("unknown outbox sink %q", cfg.Sink)
+	}
+}
+
+// WebhookSink POSTs each event as JSON and treats any 2xx response as
+// delivered.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, event.ID.String())
+	req.Header.Set(EventTypeHeader, event.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
+
+// KafkaSink writes events to a Kafka-compatible broker keyed by wallet ID,
+// so all events of a wallet land on one partition in order.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, event models.Event) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	return s.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.WalletID.String()),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: EventIDHeader, Value: []byte(event.ID.String())},
+			{Key: EventTypeHeader, Value: []byte(event.Type)},
+		},
+	})
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
+
+// FileSink appends events to a file as JSON lines. It is meant for tests
+// and local development.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox file: %w", err)
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Publish(_ context.Context, event models.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// MultiSink publishes each event to every sink in turn. When one fails the
+// event is retried on all of them, which at-least-once consumers tolerate.
+type MultiSink []Sink
+
+func (s MultiSink) Publish(ctx context.Context, event models.Event) error {
+	for _, sink := range s {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s MultiSink) Close() error {
+	var errs []error
+	for _, sink := range s {
+		errs = append(errs, sink.Close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/itk/wallet/migrations"
+)
+
+func TestLoad_Embedded(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, m := range loaded {
+		if m.Version != i+1 {
+			t.Errorf("migration %d_%s: expected version %d, versions must be sequential", m.Version, m.Name, i+1)
+		}
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "missing down",
+			fsys: fstest.MapFS{
+				"0001_wallet.up.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+		{
+			name: "mismatched names",
+			fsys: fstest.MapFS{
+				"0001_wallet.up.sql":    {Data: []byte("SELECT 1;")},
+				"0001_wallets.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); err == nil {
+				t.Error("Load() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Package ratelimit implements token-bucket rate limiting with pluggable
+// bucket storage, so limits can be enforced per process or shared across
+// replicas.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	BackendNone     = "none"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+type Config struct {
+	Backend string
+	Client  Limit
+	Wallet  Limit
+}
+
+// Limit is a token bucket refilled at Rate tokens per second that holds at
+// most Burst tokens. A zero Rate or Burst disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// RefillTime is how long an empty bucket takes to fill up.
+func (l Limit) RefillTime() time.Duration {
+	if !l.Enabled() {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(float64(l.Burst) / l.Rate * float64(time.Second)))
+}
+
+// RefillTime is the longest refill time of the enabled limits. A bucket idle
+// for that long is full under any of them.
+func (c Config) RefillTime() time.Duration {
+	return max(c.Client.RefillTime(), c.Wallet.RefillTime())
+}
+
+// Decision is the outcome of taking a token. RetryAfter is set when the
+// request was rejected and tells how long until a token becomes available.
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key. Take refills the bucket for the elapsed
+// time and removes one token if one is available.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// RetryAfter returns how long it takes to refill from tokens to one token.
+func RetryAfter(tokens float64, limit Limit) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / limit.Rate * float64(time.Second)))
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     Limit
+}
+
+// full reports whether the bucket has had time to refill completely.
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.updatedAt).Seconds()*b.limit.Rate >= float64(b.limit.Burst)
+}
+
+// MemoryStore keeps buckets in process memory. Each replica enforces the
+// limits on its own, so the effective limit scales with the replica count.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*limit.Rate)
+	b.updatedAt = now
+
+	if b.tokens < 1 {
+		return Decision{RetryAfter: RetryAfter(b.tokens, limit)}, nil
+	}
+	b.tokens--
+
+	return Decision{Allowed: true}, nil
+}
+
+// sweep drops buckets idle long enough to have refilled completely, since a
+// full bucket is the same as a missing one. It runs at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if b.full(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if decision, _ := store.Take(ctx, "a", limit); !decision.Allowed {
+			t.Fatalf("request %d within burst was rejected", i+1)
+		}
+	}
+
+	decision, _ := store.Take(ctx, "a", limit)
+	if decision.Allowed {
+		t.Fatal("request above burst was allowed")
+	}
+	if decision.RetryAfter != 500*time.Millisecond {
+		t.Errorf("RetryAfter = %s, want 500ms", decision.RetryAfter)
+	}
+
+	if decision, _ := store.Take(ctx, "b", limit); !decision.Allowed {
+		t.Error("buckets are not independent per key")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if decision, _ := store.Take(ctx, "a", limit); !decision.Allowed {
+		t.Error("request after refill was rejected")
+	}
+	if decision, _ := store.Take(ctx, "a", limit); decision.Allowed {
+		t.Error("refill granted more than one token")
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Take(ctx, "slow", Limit{Rate: 0.001, Burst: 1})
+	store.Take(ctx, "fast", Limit{Rate: 10, Burst: 1})
+
+	now = now.Add(2 * time.Minute)
+	store.Take(ctx, "other", Limit{Rate: 10, Burst: 1})
+
+	if _, ok := store.buckets["fast"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+	if _, ok := store.buckets["slow"]; !ok {
+		t.Error("bucket still refilling was swept")
+	}
+}
+
+func TestConfig_RefillTime(t *testing.T) {
+	cfg := Config{Client: Limit{Rate: 10, Burst: 20}, Wallet: Limit{Rate: 0.5, Burst: 5}}
+	if got := cfg.RefillTime(); got != 10*time.Second {
+		t.Errorf("RefillTime = %s, want 10s", got)
+	}
+
+	cfg.Wallet = Limit{}
+	if got := cfg.RefillTime(); got != 2*time.Second {
+		t.Errorf("RefillTime with wallet limit disabled = %s, want 2s", got)
+	}
+}
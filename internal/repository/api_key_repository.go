@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
+
+type APIKeyInterface interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey, keyHash string) (*models.APIKey, error)
+	FindAPIKey(ctx context.Context, keyHash string) (*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+}
+
+const apiKeyColumns = "id, name, principal, role, created_at, revoked_at"
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key models.APIKey, keyHash string) (*models.APIKey, error) {
+	created, err := scanAPIKey(r.db.QueryRowContext(ctx,
+		"INSERT INTO api_keys (id, name, key_hash, principal, role) VALUES ($1, $2, $3, $4, $5) RETURNING "+apiKeyColumns,
+		uuid.New(), key.Name, keyHash, key.Principal, key.Role))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return created, nil
+}
+
+// FindAPIKey returns the active key with the given SHA-256 hash.
+func (r *APIKeyRepository) FindAPIKey(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx,
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL", keyHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func scanAPIKey(row *sql.Row) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := row.Scan(&key.ID, &key.Name, &key.Principal, &key.Role, &key.CreatedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
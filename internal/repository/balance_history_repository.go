@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/lib/pq"
+)
+
+type HistoryInterface interface {
+	GetBalanceAt(ctx context.Context, walletID uuid.UUID, currency models.Currency, at time.Time) (int64, error)
+	SnapshotBalances(ctx context.Context, every int) (int, error)
+}
+
+// balanceAtQuery starts from the latest snapshot taken at or before $3 and
+// adds the operations recorded after it up to $3. The next snapshot bounds
+// the scan, so the work is limited to the operations between two snapshots.
+var balanceAtQuery = `
+WITH base AS (
+	SELECT seq, balance FROM balance_snapshots
+	WHERE wallet_id = $1 AND currency = $2 AND as_of <= $3
+	ORDER BY seq DESC LIMIT 1
+), bound AS (
+	SELECT seq FROM balance_snapshots
+	WHERE wallet_id = $1 AND currency = $2 AND as_of > $3
+	ORDER BY seq LIMIT 1
+)
+SELECT COALESCE((SELECT balance FROM base), 0) + COALESCE(SUM(` + signedAmount("$4") + `), 0)::BIGINT
+FROM wallet_operations
+WHERE wallet_id = $1 AND currency = $2 AND created_at <= $3
+	AND seq > COALESCE((SELECT seq FROM base), 0)
+	AND seq < COALESCE((SELECT seq FROM bound), 9223372036854775807)`
+
+// snapshotCandidatesQuery finds the balances with at least $1 operations
+// since their latest snapshot.
+const snapshotCandidatesQuery = `
+SELECT b.wallet_id, b.currency
+FROM wallet_balances b
+LEFT JOIN LATERAL (
+	SELECT MAX(seq) AS seq FROM balance_snapshots s
+	WHERE s.wallet_id = b.wallet_id AND s.currency = b.currency
+) s ON TRUE
+WHERE (
+	SELECT COUNT(*) FROM wallet_operations o
+	WHERE o.wallet_id = b.wallet_id AND o.currency = b.currency AND o.seq > COALESCE(s.seq, 0)
+) >= $1`
+
+// GetBalanceAt computes the balance of the wallet at the given instant from
+// the ledger. The stored balance is not consulted, so the result is what the
+// operation history proves.
+func (r *WalletRepository) GetBalanceAt(ctx context.Context, walletID uuid.UUID, currency models.Currency, at time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.GetBalanceAt")
+	defer span.End()
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM wallets WHERE id = $1)", walletID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check wallet: %w", err)
+	}
+	if !exists {
+		return 0, models.ErrWalletNotFound
+	}
+
+	var balance int64
+	err = r.db.QueryRowContext(ctx, balanceAtQuery, walletID, currency, at, pq.Array(debitTypes())).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// SnapshotBalances records a snapshot of every balance with at least every
+// operations since its latest snapshot and returns how many it recorded.
+func (r *WalletRepository) SnapshotBalances(ctx context.Context, every int) (int, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.SnapshotBalances")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, snapshotCandidatesQuery, every)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find balances to snapshot: %w", err)
+	}
+
+	type balanceKey struct {
+		walletID uuid.UUID
+		currency models.Currency
+	}
+	var candidates []balanceKey
+	for rows.Next() {
+		var key balanceKey
+		if err := rows.Scan(&key.walletID, &key.currency); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		candidates = append(candidates, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to find balances to snapshot: %w", err)
+	}
+
+	taken := 0
+	for _, key := range candidates {
+		if err := ctx.Err(); err != nil {
+			return taken, err
+		}
+		ok, err := r.snapshotBalance(ctx, key.walletID, key.currency)
+		if err != nil {
+			return taken, err
+		}
+		if ok {
+			taken++
+		}
+	}
+
+	return taken, nil
+}
+
+// snapshotBalance holds the wallet lock while summing the ledger, so no
+// operation with a lower seq can commit after the snapshot is taken.
+func (r *WalletRepository) snapshotBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := r.lockWallet(ctx, tx, walletID); err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		WITH base AS (
+			SELECT seq, balance FROM balance_snapshots
+			WHERE wallet_id = $1 AND currency = $2
+			ORDER BY seq DESC LIMIT 1
+		)
+		INSERT INTO balance_snapshots (wallet_id, currency, seq, balance, as_of)
+		SELECT $1, $2, MAX(seq), COALESCE((SELECT balance FROM base), 0) + SUM(`+signedAmount("$3")+`), MAX(created_at)
+		FROM wallet_operations
+		WHERE wallet_id = $1 AND currency = $2 AND seq > COALESCE((SELECT seq FROM base), 0)
+		HAVING COUNT(*) > 0
+		ON CONFLICT DO NOTHING`,
+		walletID, currency, pq.Array(debitTypes()))
+	if err != nil {
+		return false, fmt.Errorf("failed to snapshot balance: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, tx.Commit()
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/metrics"
+	"github.com/itk/wallet/internal/models"
+)
+
+type BatchInterface interface {
+	ApplyBatch(ctx context.Context, mode models.BatchMode, items []models.BatchItem, newWallets []uuid.UUID, owner string) ([]models.BatchResult, error)
+}
+
+// ApplyBatch applies all items in one transaction. newWallets are created in
+// that transaction too, and granted to owner unless it is empty, so an atomic
+// batch that rolls back leaves no wallets behind. Every wallet is locked
+// once, in UUID order, before any item runs, so concurrent batches and
+// transfers over the same wallets cannot deadlock. In atomic mode the first
+// failing item rolls the whole batch back and is returned as a
+// *models.BatchItemError. In best-effort mode each item runs under a
+// savepoint and failures are reported in its result.
+func (r *WalletRepository) ApplyBatch(ctx context.Context, mode models.BatchMode, items []models.BatchItem, newWallets []uuid.UUID, owner string) ([]models.BatchResult, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.ApplyBatch")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	firstItem := make(map[uuid.UUID]int, len(items))
+	for _, item := range items {
+		if _, ok := firstItem[item.WalletID]; !ok {
+			firstItem[item.WalletID] = item.Index
+		}
+	}
+
+	walletIDs := make([]uuid.UUID, 0, len(firstItem))
+	for walletID := range firstItem {
+		walletIDs = append(walletIDs, walletID)
+	}
+	slices.SortFunc(walletIDs, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+	lockErrs := make(map[uuid.UUID]error)
+	for _, walletID := range newWallets {
+		if err := r.createBatchWallet(ctx, tx, walletID, owner); err != nil {
+			if mode == models.BatchModeAtomic || !errors.Is(err, models.ErrForbidden) {
+				return nil, &models.BatchItemError{Index: firstItem[walletID], Err: err}
+			}
+			lockErrs[walletID] = err
+		}
+	}
+
+	statuses := make(map[uuid.UUID]models.WalletStatus, len(walletIDs))
+	lockStart := time.Now()
+	for _, walletID := range walletIDs {
+		if _, ok := lockErrs[walletID]; ok {
+			continue
+		}
+		status, err := r.lockWallet(ctx, tx, walletID)
+		if err != nil {
+			if mode == models.BatchModeAtomic || !errors.Is(err, models.ErrWalletNotFound) {
+				return nil, &models.BatchItemError{Index: firstItem[walletID], Err: err}
+			}
+			lockErrs[walletID] = err
+			continue
+		}
+		statuses[walletID] = status
+	}
+	metrics.ObserveLockWait("BATCH", lockStart)
+
+	results := make([]models.BatchResult, 0, len(items))
+	for _, item := range items {
+		result := models.BatchResult{Index: item.Index}
+		if err, ok := lockErrs[item.WalletID]; ok {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+
+		if mode == models.BatchModeBestEffort {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+				return nil, fmt.Errorf("failed to create savepoint: %w", err)
+			}
+		}
+
+		op, err := r.applyOperation(ctx, tx, item.WalletID, statuses[item.WalletID], item.Currency, item.Operation, item.Amount)
+		if err != nil {
+			if mode == models.BatchModeAtomic {
+				return nil, &models.BatchItemError{Index: item.Index, Err: err}
+			}
+			if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item"); rbErr != nil {
+				return nil, fmt.Errorf("failed to roll back batch item %d: %w", item.Index, rbErr)
+			}
+			result.Err = err
+		} else {
+			result.Operation = op
+		}
+		results = append(results, result)
+	}
+
+	return results, commit(ctx, tx)
+}
+
+// createBatchWallet creates the wallet for owner. A wallet someone else
+// created since the batch was authorized is only usable if owner owns it.
+func (r *WalletRepository) createBatchWallet(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, owner string) error {
+	result, err := tx.ExecContext(ctx, "INSERT INTO wallets (id, created_at) VALUES ($1, NOW()) ON CONFLICT (id) DO NOTHING", walletID)
+	if err != nil {
+		return fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if owner == "" {
+		return nil
+	}
+
+	if rowsAffected > 0 {
+		_, err := tx.ExecContext(ctx, "INSERT INTO wallet_owners (wallet_id, principal) VALUES ($1, $2)", walletID, owner)
+		if err != nil {
+			return fmt.Errorf("failed to add wallet owner: %w", err)
+		}
+		return nil
+	}
+
+	var owned bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM wallet_owners WHERE wallet_id = $1 AND principal = $2)", walletID, owner).Scan(&owned)
+	if err != nil {
+		return fmt.Errorf("failed to check wallet owner: %w", err)
+	}
+	if !owned {
+		return models.ErrForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+type HoldInterface interface {
+	CreateHold(ctx context.Context, walletID uuid.UUID, currency models.Currency, amount int64, ttl time.Duration) (*models.Hold, error)
+	GetHold(ctx context.Context, holdID uuid.UUID) (*models.Hold, error)
+	CaptureHold(ctx context.Context, holdID uuid.UUID, amount int64) (*models.Hold, error)
+	ReleaseHold(ctx context.Context, holdID uuid.UUID) (*models.Hold, error)
+	ExpireHolds(ctx context.Context) (int64, error)
+}
+
+const holdColumns = "id, wallet_id, currency, amount, captured_amount, status, expires_at, created_at, updated_at"
+
+func (r *WalletRepository) CreateHold(ctx context.Context, walletID uuid.UUID, currency models.Currency, amount int64, ttl time.Duration) (*models.Hold, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.CreateHold")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, err := r.lockWallet(ctx, tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := status.CheckDebit(); err != nil {
+		return nil, err
+	}
+
+	balance, err := r.getBalanceForUpdate(ctx, tx, walletID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := r.heldAmount(ctx, tx, walletID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if balance-held < amount {
+		return nil, models.ErrInsufficientFunds
+	}
+
+	hold, err := scanHold(tx.QueryRowContext(ctx,
+		"INSERT INTO wallet_holds (id, wallet_id, currency, amount, expires_at) VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5)) RETURNING "+holdColumns,
+		uuid.New(), walletID, currency, amount, ttl.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+
+	return hold, commit(ctx, tx)
+}
+
+func (r *WalletRepository) GetHold(ctx context.Context, holdID uuid.UUID) (*models.Hold, error) {
+	hold, err := scanHold(r.db.QueryRowContext(ctx, "SELECT "+holdColumns+" FROM wallet_holds WHERE id = $1", holdID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+// CaptureHold settles the hold against the posted balance. A partial capture
+// releases the remainder of the hold.
+func (r *WalletRepository) CaptureHold(ctx context.Context, holdID uuid.UUID, amount int64) (*models.Hold, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.CaptureHold")
+	defer span.End()
+
+	tx, hold, status, err := r.lockActiveHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := status.CheckDebit(); err != nil {
+		return nil, err
+	}
+
+	if amount > hold.Amount {
+		return nil, models.ErrCaptureExceeds
+	}
+
+	balance, err := r.getBalanceForUpdate(ctx, tx, hold.WalletID, hold.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if balance < amount {
+		return nil, models.ErrInsufficientFunds
+	}
+
+	if err := r.checkOutflowLimits(ctx, tx, hold.WalletID, hold.Currency, amount); err != nil {
+		return nil, err
+	}
+
+	if err := r.setBalance(ctx, tx, hold.WalletID, hold.Currency, balance-amount); err != nil {
+		return nil, err
+	}
+
+	if err := r.insertOperation(ctx, tx, &models.WalletOperation{
+		ID:            uuid.New(),
+		WalletID:      hold.WalletID,
+		Operation:     models.OperationTypeCapture,
+		Currency:      hold.Currency,
+		Amount:        amount,
+		BalanceBefore: balance,
+		BalanceAfter:  balance - amount,
+		HoldID:        &hold.ID,
+	}); err != nil {
+		return nil, err
+	}
+
+	hold, err = scanHold(tx.QueryRowContext(ctx,
+		"UPDATE wallet_holds SET status = $1, captured_amount = $2, updated_at = NOW() WHERE id = $3 RETURNING "+holdColumns,
+		models.HoldStatusCaptured, amount, holdID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture hold: %w", err)
+	}
+
+	return hold, commit(ctx, tx)
+}
+
+func (r *WalletRepository) ReleaseHold(ctx context.Context, holdID uuid.UUID) (*models.Hold, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.ReleaseHold")
+	defer span.End()
+
+	tx, _, _, err := r.lockActiveHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hold, err := scanHold(tx.QueryRowContext(ctx,
+		"UPDATE wallet_holds SET status = $1, updated_at = NOW() WHERE id = $2 RETURNING "+holdColumns,
+		models.HoldStatusReleased, holdID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to release hold: %w", err)
+	}
+
+	return hold, commit(ctx, tx)
+}
+
+func (r *WalletRepository) ExpireHolds(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE wallet_holds SET status = $1, updated_at = NOW() WHERE status = $2 AND expires_at <= NOW()",
+		models.HoldStatusExpired, models.HoldStatusActive)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire holds: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// lockActiveHold opens a transaction holding the wallet lock and the hold row
+// lock, in that order, so hold changes serialize with other balance changes.
+func (r *WalletRepository) lockActiveHold(ctx context.Context, holdID uuid.UUID) (*sql.Tx, *models.Hold, models.WalletStatus, error) {
+	hold, err := r.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	status, err := r.lockWallet(ctx, tx, hold.WalletID)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, "", err
+	}
+
+	var expired bool
+	err = tx.QueryRowContext(ctx, "SELECT status, expires_at <= NOW() FROM wallet_holds WHERE id = $1 FOR UPDATE", holdID).
+		Scan(&hold.Status, &expired)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, "", fmt.Errorf("failed to lock hold: %w", err)
+	}
+
+	if hold.Status != models.HoldStatusActive || expired {
+		tx.Rollback()
+		return nil, nil, "", models.ErrHoldNotActive
+	}
+
+	return tx, hold, status, nil
+}
+
+func (r *WalletRepository) heldAmount(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, currency models.Currency) (int64, error) {
+	var held int64
+	err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(amount), 0) FROM wallet_holds WHERE wallet_id = $1 AND currency = $2 AND status = $3 AND expires_at > NOW()",
+		walletID, currency, models.HoldStatusActive).Scan(&held)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get held amount: %w", err)
+	}
+
+	return held, nil
+}
+
+func scanHold(row *sql.Row) (*models.Hold, error) {
+	var hold models.Hold
+	err := row.Scan(&hold.ID, &hold.WalletID, &hold.Currency, &hold.Amount, &hold.CapturedAmount,
+		&hold.Status, &hold.ExpiresAt, &hold.CreatedAt, &hold.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
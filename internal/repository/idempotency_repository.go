@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+type IdempotencyRepository struct {
+	db    *sql.DB
+	lease time.Duration
+}
+
+// NewIdempotencyRepository stores idempotency keys. A reservation that has
+// neither taken effect nor completed within lease, e.g. because its process
+// crashed, may be taken over by a retry.
+func NewIdempotencyRepository(db *sql.DB, lease time.Duration) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db:    db,
+		lease: lease,
+	}
+}
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey binds the request's reserved idempotency key to ctx.
+// Write paths mark the key applied in the same transaction as their balance
+// change, so a key is never released once its effect has committed.
+func WithIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, record)
+}
+
+// markApplied records in tx that the request bound to ctx took effect. It
+// fails if another request has taken the reservation over in the meantime,
+// which rolls the transaction back instead of applying the change twice.
+func markApplied(ctx context.Context, tx *sql.Tx) error {
+	record, ok := ctx.Value(idempotencyKeyCtx{}).(*models.IdempotencyRecord)
+	if !ok {
+		return nil
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE idempotency_keys SET applied_at = NOW() WHERE principal_id = $1 AND key = $2 AND reservation = $3 AND applied_at IS NULL",
+		record.PrincipalID, record.Key, record.Reservation)
+	if err != nil {
+		return fmt.Errorf("failed to mark idempotency key applied: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrIdempotencyKeyLost
+	}
+
+	return nil
+}
+
+// commit commits a write path's transaction, marking its idempotency key
+// applied first.
+func commit(ctx context.Context, tx *sql.Tx) error {
+	if err := markApplied(ctx, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type IdempotencyInterface interface {
+	Reserve(ctx context.Context, principalID, key string, requestHash string) (*models.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord, status int, body []byte) error
+	Release(ctx context.Context, record *models.IdempotencyRecord) error
+}
+
+// Reserve claims the key for the current request. Keys are scoped to the principal,
+// so two callers picking the same key never see each other's responses. It returns
+// created=true when the key was free or its reservation went stale, otherwise the
+// record stored by the request that claimed it first.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, principalID, key string, requestHash string) (*models.IdempotencyRecord, bool, error) {
+	reservation := uuid.New()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (principal_id, key, request_hash, reservation) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (principal_id, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, reservation = EXCLUDED.reservation, reserved_at = NOW(), created_at = NOW()
+			WHERE idempotency_keys.completed_at IS NULL AND idempotency_keys.applied_at IS NULL
+				AND idempotency_keys.reserved_at < NOW() - make_interval(secs => $5)`,
+		principalID, key, requestHash, reservation, r.lease.Seconds())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected > 0 {
+		return &models.IdempotencyRecord{PrincipalID: principalID, Key: key, RequestHash: requestHash, Reservation: reservation}, true, nil
+	}
+
+	var record models.IdempotencyRecord
+	var status sql.NullInt32
+	err = r.db.QueryRowContext(ctx, `
+		SELECT principal_id, key, request_hash, reservation, response_status, response_body, created_at, reserved_at, applied_at, completed_at
+		FROM idempotency_keys WHERE principal_id = $1 AND key = $2`,
+		principalID, key).
+		Scan(&record.PrincipalID, &record.Key, &record.RequestHash, &record.Reservation, &status, &record.ResponseBody,
+			&record.CreatedAt, &record.ReservedAt, &record.AppliedAt, &record.CompletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// The previous holder released the key between our insert and select.
+			return r.Reserve(ctx, principalID, key, requestHash)
+		}
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	record.ResponseStatus = int(status.Int32)
+
+	return &record, false, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord, status int, body []byte) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET response_status = $1, response_body = $2, completed_at = NOW() WHERE principal_id = $3 AND key = $4 AND reservation = $5",
+		status, body, record.PrincipalID, record.Key, record.Reservation)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Release frees a key whose request failed before taking effect. A key
+// marked applied stays, so a retry cannot repeat the balance change.
+func (r *IdempotencyRepository) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE principal_id = $1 AND key = $2 AND reservation = $3 AND completed_at IS NULL AND applied_at IS NULL",
+		record.PrincipalID, record.Key, record.Reservation)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes keys created before cutoff. A retry after that
+// is treated as a new request.
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE created_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/lib/pq"
+)
+
+type LimitInterface interface {
+	GetLimits(ctx context.Context, walletID *uuid.UUID, currency models.Currency) (*models.WithdrawalLimits, error)
+	SetLimits(ctx context.Context, limits models.WithdrawalLimits) (*models.WithdrawalLimits, error)
+	DeleteLimits(ctx context.Context, walletID *uuid.UUID, currency models.Currency) error
+}
+
+const limitColumns = "wallet_id, currency, max_single, daily, monthly, updated_at"
+
+// GetLimits returns the policy stored for the wallet, or the default policy when walletID is nil.
+func (r *WalletRepository) GetLimits(ctx context.Context, walletID *uuid.UUID, currency models.Currency) (*models.WithdrawalLimits, error) {
+	limits, err := scanLimits(r.db.QueryRowContext(ctx,
+		"SELECT "+limitColumns+" FROM withdrawal_limits WHERE wallet_id IS NOT DISTINCT FROM $1 AND currency = $2",
+		walletID, currency))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrLimitsNotFound
+		}
+		return nil, fmt.Errorf("failed to get limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+func (r *WalletRepository) SetLimits(ctx context.Context, limits models.WithdrawalLimits) (*models.WithdrawalLimits, error) {
+	saved, err := scanLimits(r.db.QueryRowContext(ctx, `
+		INSERT INTO withdrawal_limits (wallet_id, currency, max_single, daily, monthly)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (wallet_id, currency) DO UPDATE
+		SET max_single = EXCLUDED.max_single, daily = EXCLUDED.daily, monthly = EXCLUDED.monthly, updated_at = NOW()
+		RETURNING `+limitColumns,
+		limits.WalletID, limits.Currency, limits.MaxSingle, limits.Daily, limits.Monthly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set limits: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (r *WalletRepository) DeleteLimits(ctx context.Context, walletID *uuid.UUID, currency models.Currency) error {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM withdrawal_limits WHERE wallet_id IS NOT DISTINCT FROM $1 AND currency = $2", walletID, currency)
+	if err != nil {
+		return fmt.Errorf("failed to delete limits: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrLimitsNotFound
+	}
+
+	return nil
+}
+
+// checkOutflowLimits must run while the wallet row is locked, so concurrent
+// outflows observe each other's ledger entries.
+func (r *WalletRepository) checkOutflowLimits(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, currency models.Currency, amount int64) error {
+	limits, err := scanLimits(tx.QueryRowContext(ctx,
+		"SELECT "+limitColumns+" FROM withdrawal_limits WHERE (wallet_id = $1 OR wallet_id IS NULL) AND currency = $2 ORDER BY wallet_id NULLS LAST LIMIT 1",
+		walletID, currency))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get limits: %w", err)
+	}
+
+	if limits.MaxSingle != nil && amount > *limits.MaxSingle {
+		return fmt.Errorf("%w: single withdrawal limit is %d", models.ErrLimitExceeded, *limits.MaxSingle)
+	}
+
+	if limits.Daily == nil && limits.Monthly == nil {
+		return nil
+	}
+
+	var daily, monthly int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount) FILTER (WHERE created_at >= date_trunc('day', NOW())), 0), COALESCE(SUM(amount), 0)
+		FROM wallet_operations
+		WHERE wallet_id = $1 AND currency = $2 AND created_at >= date_trunc('month', NOW())
+			AND (operation_type = ANY($3) OR (operation_type = $4 AND balance_after < balance_before))`,
+		walletID, currency, pq.Array(outflowTypes()), models.OperationTypeReversal).Scan(&daily, &monthly)
+	if err != nil {
+		return fmt.Errorf("failed to get outflow totals: %w", err)
+	}
+
+	if limits.Daily != nil && daily+amount > *limits.Daily {
+		return fmt.Errorf("%w: daily withdrawal limit is %d", models.ErrLimitExceeded, *limits.Daily)
+	}
+
+	if limits.Monthly != nil && monthly+amount > *limits.Monthly {
+		return fmt.Errorf("%w: monthly withdrawal limit is %d", models.ErrLimitExceeded, *limits.Monthly)
+	}
+
+	return nil
+}
+
+func outflowTypes() []string {
+	return operationTypeStrings(models.OutflowOperationTypes)
+}
+
+// operationTypeStrings converts operation types for use with pq.Array.
+func operationTypeStrings(operationTypes []models.OperationType) []string {
+	types := make([]string, len(operationTypes))
+	for i, t := range operationTypes {
+		types[i] = string(t)
+	}
+	return types
+}
+
+func scanLimits(row *sql.Row) (*models.WithdrawalLimits, error) {
+	var limits models.WithdrawalLimits
+	err := row.Scan(&limits.WalletID, &limits.Currency, &limits.MaxSingle, &limits.Daily, &limits.Monthly, &limits.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &limits, nil
+}
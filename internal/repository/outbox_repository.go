@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/lib/pq"
+)
+
+// relayLockID identifies the advisory lock held by the replica currently
+// relaying outbox events. A single relay keeps events of a wallet in order.
+const relayLockID int64 = 7_240_118_513
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+type OutboxInterface interface {
+	RelayPending(ctx context.Context, limit int, publish func(context.Context, models.Event) error, backoff func(attempts int) time.Duration) (published, failed int, err error)
+}
+
+// DisableEvents stops recording ledger operations in the outbox. Call it
+// before use when nothing relays events, so the table does not grow forever.
+func (r *WalletRepository) DisableEvents() {
+	r.skipEvents = true
+}
+
+// insertEvent records a ledger operation in the outbox. It runs in the
+// transaction that writes the operation, so an event exists exactly when
+// the balance change is committed.
+func (r *WalletRepository) insertEvent(ctx context.Context, tx *sql.Tx, op *models.WalletOperation) error {
+	if r.skipEvents {
+		return nil
+	}
+
+	payload, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO outbox_events (id, wallet_id, event_type, payload, created_at) VALUES ($1, $2, $3, $4, $5)",
+		uuid.New(), op.WalletID, models.OperationEventType(op.Operation), payload, op.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record event: %w", err)
+	}
+
+	return nil
+}
+
+// pendingEventsQuery selects due events in seq order, skipping every wallet
+// whose oldest unpublished event is still waiting for a retry so that later
+// events never overtake it.
+const pendingEventsQuery = `
+SELECT e.id, e.seq, e.wallet_id, e.event_type, e.payload, e.created_at, e.attempts
+FROM outbox_events e
+WHERE e.published_at IS NULL
+	AND e.next_attempt_at <= NOW()
+	AND NOT EXISTS (
+		SELECT 1 FROM outbox_events p
+		WHERE p.wallet_id = e.wallet_id AND p.published_at IS NULL AND p.seq < e.seq AND p.next_attempt_at > NOW()
+	)
+ORDER BY e.seq
+LIMIT $1`
+
+// RelayPending hands up to limit due events to publish, in seq order, and
+// records the outcome. It returns without doing anything when another
+// replica holds the relay lock. After a failure the remaining events of that
+// wallet are left for a later run, and the failed one is retried after
+// backoff. Events are marked published only when the transaction commits,
+// so a crash may deliver an event again but never loses one.
+func (r *OutboxRepository) RelayPending(ctx context.Context, limit int, publish func(context.Context, models.Event) error, backoff func(attempts int) time.Duration) (int, int, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", relayLockID).Scan(&locked); err != nil {
+		return 0, 0, fmt.Errorf("failed to take relay lock: %w", err)
+	}
+	if !locked {
+		return 0, 0, nil
+	}
+
+	events, err := r.pendingEvents(ctx, tx, limit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var published []uuid.UUID
+	failed := 0
+	blocked := make(map[uuid.UUID]bool)
+	for _, event := range events {
+		if blocked[event.WalletID] {
+			continue
+		}
+
+		if err := publish(ctx, event); err != nil {
+			blocked[event.WalletID] = true
+			failed++
+			_, err = tx.ExecContext(ctx,
+				"UPDATE outbox_events SET attempts = attempts + 1, last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3) WHERE id = $1",
+				event.ID, err.Error(), backoff(event.Attempts+1).Seconds())
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to record event failure: %w", err)
+			}
+			continue
+		}
+		published = append(published, event.ID)
+	}
+
+	if len(published) > 0 {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE outbox_events SET published_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = ANY($1)",
+			pq.Array(published))
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to mark events published: %w", err)
+		}
+	}
+
+	return len(published), failed, tx.Commit()
+}
+
+// DeletePublished removes events published before cutoff. Unpublished
+// events are kept however old they are.
+func (r *OutboxRepository) DeletePublished(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM outbox_events WHERE published_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published events: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *OutboxRepository) pendingEvents(ctx context.Context, tx *sql.Tx, limit int) ([]models.Event, error) {
+	rows, err := tx.QueryContext(ctx, pendingEventsQuery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		if err := rows.Scan(&event.ID, &event.Seq, &event.WalletID, &event.Type, &event.Payload, &event.CreatedAt, &event.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get pending events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type OwnerInterface interface {
+	CreateOwnedWallet(ctx context.Context, walletID uuid.UUID, owner string) (bool, error)
+	AddWalletOwner(ctx context.Context, walletID uuid.UUID, principal string) error
+	IsWalletOwner(ctx context.Context, walletID uuid.UUID, principal string) (bool, error)
+}
+
+// CreateOwnedWallet creates the wallet and records owner in one
+// transaction, so a failure cannot leave a wallet nobody may use. An empty
+// owner creates the wallet alone. It returns false if the wallet already
+// existed, leaving its owners untouched.
+func (r *WalletRepository) CreateOwnedWallet(ctx context.Context, walletID uuid.UUID, owner string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO wallets (id, created_at) VALUES ($1, NOW()) ON CONFLICT (id) DO NOTHING", walletID)
+	if err != nil {
+		return false, fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if owner != "" {
+		_, err := tx.ExecContext(ctx, "INSERT INTO wallet_owners (wallet_id, principal) VALUES ($1, $2)", walletID, owner)
+		if err != nil {
+			return false, fmt.Errorf("failed to add wallet owner: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *WalletRepository) AddWalletOwner(ctx context.Context, walletID uuid.UUID, principal string) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO wallet_owners (wallet_id, principal) VALUES ($1, $2) ON CONFLICT DO NOTHING", walletID, principal)
+	if err != nil {
+		return fmt.Errorf("failed to add wallet owner: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WalletRepository) IsWalletOwner(ctx context.Context, walletID uuid.UUID, principal string) (bool, error) {
+	var owner bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM wallet_owners WHERE wallet_id = $1 AND principal = $2)", walletID, principal).Scan(&owner)
+	if err != nil {
+		return false, fmt.Errorf("failed to check wallet owner: %w", err)
+	}
+
+	return owner, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/itk/wallet/internal/ratelimit"
+)
+
+// RateLimitRepository keeps token buckets in PostgreSQL so that all replicas
+// share the same limits. It implements ratelimit.Store.
+type RateLimitRepository struct {
+	db *sql.DB
+}
+
+func NewRateLimitRepository(db *sql.DB) *RateLimitRepository {
+	return &RateLimitRepository{
+		db: db,
+	}
+}
+
+// takeTokenQuery refills and takes from a bucket in one statement. The row
+// lock taken by the upsert serializes concurrent requests for the same key.
+const takeTokenQuery = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES ($1, $3::float8 - 1, TRUE, NOW())
+ON CONFLICT (key) DO UPDATE SET
+	allowed = LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at)::float8 * $2::float8) >= 1,
+	tokens = LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at)::float8 * $2::float8)
+		- CASE WHEN LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at)::float8 * $2::float8) >= 1 THEN 1 ELSE 0 END,
+	updated_at = NOW()
+RETURNING tokens, allowed`
+
+func (r *RateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Decision, error) {
+	var tokens float64
+	var allowed bool
+	if err := r.db.QueryRowContext(ctx, takeTokenQuery, key, limit.Rate, limit.Burst).Scan(&tokens, &allowed); err != nil {
+		return ratelimit.Decision{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	if !allowed {
+		return ratelimit.Decision{RetryAfter: ratelimit.RetryAfter(tokens, limit)}, nil
+	}
+
+	return ratelimit.Decision{Allowed: true}, nil
+}
+
+// DeleteIdle removes buckets untouched for idle. Pass at least the longest
+// refill time of the configured limits, so only full buckets go: a full
+// bucket behaves exactly like a missing one.
+func (r *RateLimitRepository) DeleteIdle(ctx context.Context, idle time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - make_interval(secs => $1)", idle.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete idle rate limit buckets: %w", err)
+	}
+
+	return result.RowsAffected()
+}
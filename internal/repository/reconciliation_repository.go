@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/lib/pq"
+)
+
+// reconcileLockID identifies the advisory lock held for the whole of a
+// reconciliation run, so a job scheduled on every replica runs once.
+const reconcileLockID int64 = 7_240_118_514
+
+type ReconciliationInterface interface {
+	WithReconcileLock(ctx context.Context, fn func(ctx context.Context) error) error
+	FindBalanceMismatches(ctx context.Context) (int, []models.BalanceMismatch, error)
+	RepairBalance(ctx context.Context, runID, walletID uuid.UUID, currency models.Currency, actor string) (*models.BalanceAdjustment, error)
+	SaveReconciliationRun(ctx context.Context, report *models.ReconciliationReport) error
+}
+
+// ledgerBalancesQuery sums the signed ledger entries of every wallet and
+// currency next to the stored balance. A full join also catches stored
+// balances without any ledger entry and ledger entries without a balance row.
+var ledgerBalancesQuery = `
+WITH ledger AS (
+	SELECT wallet_id, currency, SUM(` + signedAmount("$1") + `)::BIGINT AS balance
+	FROM wallet_operations
+	GROUP BY wallet_id, currency
+)
+SELECT COALESCE(b.wallet_id, l.wallet_id), COALESCE(b.currency, l.currency), COALESCE(b.balance, 0), COALESCE(l.balance, 0)
+FROM wallet_balances b
+FULL JOIN ledger l ON l.wallet_id = b.wallet_id AND l.currency = b.currency`
+
+// WithReconcileLock runs fn while holding the reconciliation lock, or returns
+// ErrReconcileRunning when another run holds it.
+func (r *WalletRepository) WithReconcileLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", reconcileLockID).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take reconciliation lock: %w", err)
+	}
+	if !locked {
+		return models.ErrReconcileRunning
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", reconcileLockID)
+
+	return fn(ctx)
+}
+
+// FindBalanceMismatches compares every stored balance with its ledger in one
+// snapshot, so operations committed meanwhile cannot show up as drift. It
+// returns the number of balances checked and those that differ.
+func (r *WalletRepository) FindBalanceMismatches(ctx context.Context) (int, []models.BalanceMismatch, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.FindBalanceMismatches")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, ledgerBalancesQuery, pq.Array(debitTypes()))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to compute ledger balances: %w", err)
+	}
+	defer rows.Close()
+
+	checked := 0
+	var mismatches []models.BalanceMismatch
+	for rows.Next() {
+		var m models.BalanceMismatch
+		if err := rows.Scan(&m.WalletID, &m.Currency, &m.StoredBalance, &m.LedgerBalance); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan ledger balance: %w", err)
+		}
+		checked++
+		if m.StoredBalance != m.LedgerBalance {
+			m.Difference = m.StoredBalance - m.LedgerBalance
+			mismatches = append(mismatches, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to compute ledger balances: %w", err)
+	}
+
+	return checked, mismatches, tx.Commit()
+}
+
+// RepairBalance resets the stored balance to the ledger balance and records
+// the adjustment. The ledger itself is left untouched: it is the source of
+// truth, and an entry covering the drift would make it agree with the
+// tampered value. It returns nil when the balance no longer differs.
+func (r *WalletRepository) RepairBalance(ctx context.Context, runID, walletID uuid.UUID, currency models.Currency, actor string) (*models.BalanceAdjustment, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.RepairBalance")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := r.lockWallet(ctx, tx, walletID); err != nil {
+		return nil, err
+	}
+
+	stored, err := r.getBalanceForUpdate(ctx, tx, walletID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var ledger int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM("+signedAmount("$3")+"), 0)::BIGINT FROM wallet_operations WHERE wallet_id = $1 AND currency = $2",
+		walletID, currency, pq.Array(debitTypes())).Scan(&ledger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute ledger balance: %w", err)
+	}
+	if stored == ledger {
+		return nil, nil
+	}
+
+	if err := r.setBalance(ctx, tx, walletID, currency, ledger); err != nil {
+		return nil, err
+	}
+
+	adjustment := &models.BalanceAdjustment{
+		ID:            uuid.New(),
+		RunID:         runID,
+		WalletID:      walletID,
+		Currency:      currency,
+		BalanceBefore: stored,
+		BalanceAfter:  ledger,
+		Actor:         actor,
+		Reason:        fmt.Sprintf("stored balance differed from the ledger by %d", stored-ledger),
+	}
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO balance_adjustments (id, run_id, wallet_id, currency, balance_before, balance_after, actor, reason) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at",
+		adjustment.ID, adjustment.RunID, adjustment.WalletID, adjustment.Currency, adjustment.BalanceBefore, adjustment.BalanceAfter, adjustment.Actor, adjustment.Reason).
+		Scan(&adjustment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record balance adjustment: %w", err)
+	}
+
+	return adjustment, tx.Commit()
+}
+
+func (r *WalletRepository) SaveReconciliationRun(ctx context.Context, report *models.ReconciliationReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode reconciliation report: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"INSERT INTO reconciliation_runs (id, started_at, finished_at, balances_checked, mismatches, repaired, report) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		report.ID, report.StartedAt, report.FinishedAt, report.BalancesChecked, len(report.Mismatches), report.Repaired, body)
+	if err != nil {
+		return fmt.Errorf("failed to save reconciliation run: %w", err)
+	}
+
+	return nil
+}
+
+// signedAmount is the SQL expression for the change an operation made to its
+// balance, given the placeholder bound to debitTypes(). A reversal moves the
+// balance opposite to the operation it reverses; its balance columns record
+// which way, and its amount how far.
+func signedAmount(debitTypesParam string) string {
+	return "CASE WHEN operation_type = ANY(" + debitTypesParam + ")" +
+		" OR (operation_type = '" + string(models.OperationTypeReversal) + "' AND balance_after < balance_before)" +
+		" THEN -amount ELSE amount END"
+}
+
+func debitTypes() []string {
+	return operationTypeStrings(models.DebitOperationTypes)
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/metrics"
+	"github.com/itk/wallet/internal/models"
+)
+
+type ReversalInterface interface {
+	GetOperation(ctx context.Context, operationID uuid.UUID) (*models.WalletOperation, error)
+	ReverseOperation(ctx context.Context, operationID uuid.UUID, amount *int64, allowNegative bool) (*models.WalletOperation, error)
+}
+
+func (r *WalletRepository) GetOperation(ctx context.Context, operationID uuid.UUID) (*models.WalletOperation, error) {
+	op, err := scanOperation(r.db.QueryRowContext(ctx, "SELECT "+operationColumns+" FROM wallet_operations WHERE id = $1", operationID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrOperationNotFound
+		}
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	return op, nil
+}
+
+// ReverseOperation records a reversal of the operation for amount, or for
+// everything not reversed yet when amount is nil. The reversals of an
+// operation never add up to more than its amount; they are summed under the
+// wallet lock, so concurrent reversals cannot both pass the check. Reversing
+// a credit counts against withdrawal limits like any other outflow, and may
+// take the balance below zero only when allowNegative is set.
+func (r *WalletRepository) ReverseOperation(ctx context.Context, operationID uuid.UUID, amount *int64, allowNegative bool) (*models.WalletOperation, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.ReverseOperation")
+	defer span.End()
+
+	original, err := r.GetOperation(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	if !original.Operation.IsReversible() {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotReversible, original.Operation)
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	lockStart := time.Now()
+	status, err := r.lockWallet(ctx, tx, original.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObserveLockWait(string(models.OperationTypeReversal), lockStart)
+
+	var reversed int64
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM wallet_operations WHERE reversal_of = $1", operationID).Scan(&reversed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reversed amount: %w", err)
+	}
+
+	remaining := original.Amount - reversed
+	if remaining <= 0 {
+		return nil, models.ErrAlreadyReversed
+	}
+	reverse := remaining
+	if amount != nil {
+		reverse = *amount
+	}
+	if reverse > remaining {
+		return nil, fmt.Errorf("%w: %d left to reverse", models.ErrReversalExceeds, remaining)
+	}
+
+	balance, err := r.getBalanceForUpdate(ctx, tx, original.WalletID, original.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var newBalance int64
+	if original.Operation.IsDebit() {
+		if err := status.CheckCredit(); err != nil {
+			return nil, err
+		}
+		newBalance, err = models.AddAmount(balance, reverse)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := status.CheckDebit(); err != nil {
+			return nil, err
+		}
+		held, err := r.heldAmount(ctx, tx, original.WalletID, original.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if !allowNegative && balance-held < reverse {
+			return nil, models.ErrInsufficientFunds
+		}
+		if err := r.checkOutflowLimits(ctx, tx, original.WalletID, original.Currency, reverse); err != nil {
+			return nil, err
+		}
+		newBalance = balance - reverse
+		if newBalance < 0 {
+			if err := r.allowOverdraft(ctx, tx, original.WalletID, original.Currency, -newBalance); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := r.setBalance(ctx, tx, original.WalletID, original.Currency, newBalance); err != nil {
+		return nil, err
+	}
+
+	op := &models.WalletOperation{
+		ID:            uuid.New(),
+		WalletID:      original.WalletID,
+		Operation:     models.OperationTypeReversal,
+		Currency:      original.Currency,
+		Amount:        reverse,
+		BalanceBefore: balance,
+		BalanceAfter:  newBalance,
+		ReversalOf:    &original.ID,
+	}
+	if err := r.insertOperation(ctx, tx, op); err != nil {
+		return nil, err
+	}
+
+	return op, commit(ctx, tx)
+}
+
+// allowOverdraft lets the balance go as low as -overdraft. setBalance shrinks
+// the allowance again as the balance recovers.
+func (r *WalletRepository) allowOverdraft(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, currency models.Currency, overdraft int64) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE wallet_balances SET overdraft = GREATEST(overdraft, $3) WHERE wallet_id = $1 AND currency = $2",
+		walletID, currency, overdraft)
+	if err != nil {
+		return fmt.Errorf("failed to allow overdraft: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+type StatusInterface interface {
+	SetWalletStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus, reason, actor string) (*models.WalletStatusChange, error)
+	ListStatusChanges(ctx context.Context, walletID uuid.UUID) ([]models.WalletStatusChange, error)
+}
+
+const statusChangeColumns = "id, wallet_id, from_status, to_status, reason, actor, created_at"
+
+// SetWalletStatus moves the wallet to the given status and records the change.
+// CLOSED is terminal, and a wallet can only be closed once every balance is
+// zero and nothing is reserved.
+func (r *WalletRepository) SetWalletStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus, reason, actor string) (*models.WalletStatusChange, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := r.lockWallet(ctx, tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == models.WalletStatusClosed {
+		return nil, models.ErrWalletClosed
+	}
+
+	if status == models.WalletStatusClosed {
+		var notEmpty bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM wallet_balances WHERE wallet_id = $1 AND balance <> 0)
+			OR EXISTS (SELECT 1 FROM wallet_holds WHERE wallet_id = $1 AND status = $2 AND expires_at > NOW())`,
+			walletID, models.HoldStatusActive).Scan(&notEmpty)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check wallet balances: %w", err)
+		}
+		if notEmpty {
+			return nil, models.ErrWalletNotEmpty
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE wallets SET status = $1, updated_at = NOW() WHERE id = $2", status, walletID); err != nil {
+		return nil, fmt.Errorf("failed to set wallet status: %w", err)
+	}
+
+	var change models.WalletStatusChange
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO wallet_status_changes (wallet_id, from_status, to_status, reason, actor) VALUES ($1, $2, $3, $4, $5) RETURNING "+statusChangeColumns,
+		walletID, current, status, reason, actor).
+		Scan(&change.ID, &change.WalletID, &change.FromStatus, &change.ToStatus, &change.Reason, &change.Actor, &change.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record status change: %w", err)
+	}
+
+	return &change, tx.Commit()
+}
+
+func (r *WalletRepository) ListStatusChanges(ctx context.Context, walletID uuid.UUID) ([]models.WalletStatusChange, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+statusChangeColumns+" FROM wallet_status_changes WHERE wallet_id = $1 ORDER BY id DESC", walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]models.WalletStatusChange, 0)
+	for rows.Next() {
+		var change models.WalletStatusChange
+		if err := rows.Scan(&change.ID, &change.WalletID, &change.FromStatus, &change.ToStatus, &change.Reason, &change.Actor, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan status change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
@@ -1,37 +1,63 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/metrics"
 	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/itk/wallet/internal/repository")
+
 type WalletRepository struct {
-	db *sql.DB
+	db         *sql.DB
+	logger     *slog.Logger
+	skipEvents bool
 }
 
-func NewWalletRepository(db *sql.DB) *WalletRepository {
+func NewWalletRepository(db *sql.DB, logger *slog.Logger) *WalletRepository {
 	return &WalletRepository{
-		db: db,
+		db:     db,
+		logger: logger,
 	}
 }
 
 type WalletInterface interface {
-	GetBalance(ctx context.Context, walletID uuid.UUID) (int, error)
-	UpdateBalance(ctx context.Context, walletID uuid.UUID, operationType models.OperationType, amount int) (bool, error)
+	GetBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency) (int64, error)
+	GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error)
+	UpdateBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error)
 	CreateWallet(ctx context.Context, walletID uuid.UUID) (bool, error)
+	ListOperations(ctx context.Context, walletID uuid.UUID, filter models.OperationFilter) ([]models.WalletOperation, error)
+	Transfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, currency models.Currency, amount int64) (*models.Transfer, error)
+	HoldInterface
+	LimitInterface
+	StatusInterface
+	OwnerInterface
+	BatchInterface
+	ReconciliationInterface
+	HistoryInterface
+	ReversalInterface
 }
 
-func (r *WalletRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int, error) {
-	var balance int
-	err := r.db.QueryRowContext(ctx, "SELECT balance from wallets WHERE id = $1", walletID).Scan(&balance)
+func (r *WalletRepository) GetBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency) (int64, error) {
+	var balance int64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COALESCE(b.balance, 0) FROM wallets w LEFT JOIN wallet_balances b ON b.wallet_id = w.id AND b.currency = $2 WHERE w.id = $1",
+		walletID, currency).Scan(&balance)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return 0, fmt.Errorf("balance not found")
+			return 0, models.ErrWalletNotFound
 		}
 		return 0, fmt.Errorf("failed to get balance: %w", err)
 	}
@@ -39,55 +65,324 @@ func (r *WalletRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (
 	return balance, nil
 }
 
-func (r *WalletRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, operationType models.OperationType, amount int) (bool, error) {
-	var balance int
-	var newBalance int
+func (r *WalletRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	var wallet models.Wallet
+	err := r.db.QueryRowContext(ctx, "SELECT id, status, created_at, updated_at FROM wallets WHERE id = $1", walletID).
+		Scan(&wallet.ID, &wallet.Status, &wallet.CreatedAt, &wallet.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT b.currency, b.balance, b.balance - COALESCE(h.held, 0), b.updated_at
+		FROM wallet_balances b
+		LEFT JOIN (
+			SELECT currency, SUM(amount) AS held FROM wallet_holds
+			WHERE wallet_id = $1 AND status = 'ACTIVE' AND expires_at > NOW()
+			GROUP BY currency
+		) h ON h.currency = b.currency
+		WHERE b.wallet_id = $1
+		ORDER BY b.currency`, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	defer rows.Close()
+
+	wallet.Balances = []models.Balance{}
+	for rows.Next() {
+		var balance models.Balance
+		if err := rows.Scan(&balance.Currency, &balance.Balance, &balance.Available, &balance.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		wallet.Balances = append(wallet.Balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+
+	return &wallet, nil
+}
+
+func (r *WalletRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.UpdateBalance")
+	defer span.End()
 
 	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return false, err
 	}
+	defer tx.Rollback()
 
-	err = tx.QueryRowContext(ctx, "SELECT balance from wallets WHERE id = $1 FOR UPDATE", walletID).Scan(&balance)
+	lockStart := time.Now()
+	status, err := r.lockWallet(ctx, tx, walletID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return false, fmt.Errorf("wallet not found")
-		}
-		return false, fmt.Errorf("failed to get balance: %w", err)
+		return false, err
+	}
+	metrics.ObserveLockWait(string(operationType), lockStart)
+
+	if _, err := r.applyOperation(ctx, tx, walletID, status, currency, operationType, amount); err != nil {
+		return false, err
+	}
+
+	return true, commit(ctx, tx)
+}
+
+// applyOperation deposits to or withdraws from a wallet the caller has
+// already locked. All checks run before anything is written, so a domain
+// error leaves the transaction untouched.
+func (r *WalletRepository) applyOperation(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, status models.WalletStatus, currency models.Currency, operationType models.OperationType, amount int64) (*models.WalletOperation, error) {
+	balance, err := r.getBalanceForUpdate(ctx, tx, walletID, currency)
+	if err != nil {
+		return nil, err
 	}
 
+	var newBalance int64
 	switch operationType {
 	case models.OperationTypeDeposit:
-		newBalance = balance + amount
+		if err := status.CheckCredit(); err != nil {
+			return nil, err
+		}
+		newBalance, err = models.AddAmount(balance, amount)
+		if err != nil {
+			return nil, err
+		}
 	case models.OperationTypeWithdraw:
-		if balance < amount {
-			tx.Rollback()
-			return false, fmt.Errorf("insufficient funds")
+		if err := status.CheckDebit(); err != nil {
+			return nil, err
+		}
+		held, err := r.heldAmount(ctx, tx, walletID, currency)
+		if err != nil {
+			return nil, err
+		}
+		if balance-held < amount {
+			return nil, models.ErrInsufficientFunds
+		}
+		if err := r.checkOutflowLimits(ctx, tx, walletID, currency, amount); err != nil {
+			return nil, err
 		}
 		newBalance = balance - amount
 	default:
-		return false, fmt.Errorf("invalid operation type")
+		return nil, fmt.Errorf("%w: %s", models.ErrInvalidOperation, operationType)
 	}
 
-	result, err := tx.ExecContext(ctx, "UPDATE wallets SET balance = $1, updated_at = NOW() WHERE id = $2", newBalance, walletID)
+	if err := r.setBalance(ctx, tx, walletID, currency, newBalance); err != nil {
+		return nil, err
+	}
+
+	op := &models.WalletOperation{
+		ID:            uuid.New(),
+		WalletID:      walletID,
+		Operation:     operationType,
+		Currency:      currency,
+		Amount:        amount,
+		BalanceBefore: balance,
+		BalanceAfter:  newBalance,
+	}
+	if err := r.insertOperation(ctx, tx, op); err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func (r *WalletRepository) Transfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, currency models.Currency, amount int64) (*models.Transfer, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.Transfer")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return false, fmt.Errorf("failed to update wallet: %w", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock both rows in UUID order so opposite transfers cannot deadlock.
+	lockOrder := []uuid.UUID{fromWalletID, toWalletID}
+	if bytes.Compare(toWalletID[:], fromWalletID[:]) < 0 {
+		lockOrder[0], lockOrder[1] = toWalletID, fromWalletID
+	}
+
+	balances := make(map[uuid.UUID]int64, 2)
+	statuses := make(map[uuid.UUID]models.WalletStatus, 2)
+	lockStart := time.Now()
+	for _, walletID := range lockOrder {
+		status, err := r.lockWallet(ctx, tx, walletID)
+		if err != nil {
+			return nil, err
+		}
+		balance, err := r.getBalanceForUpdate(ctx, tx, walletID, currency)
+		if err != nil {
+			return nil, err
+		}
+		balances[walletID] = balance
+		statuses[walletID] = status
+	}
+	metrics.ObserveLockWait("TRANSFER", lockStart)
+
+	if err := statuses[fromWalletID].CheckDebit(); err != nil {
+		return nil, err
+	}
+	if err := statuses[toWalletID].CheckCredit(); err != nil {
+		return nil, err
+	}
+
+	fromBalance, toBalance := balances[fromWalletID], balances[toWalletID]
+	held, err := r.heldAmount(ctx, tx, fromWalletID, currency)
+	if err != nil {
+		return nil, err
+	}
+	if fromBalance-held < amount {
+		return nil, models.ErrInsufficientFunds
+	}
+
+	if err := r.checkOutflowLimits(ctx, tx, fromWalletID, currency, amount); err != nil {
+		return nil, err
+	}
+
+	toBalanceAfter, err := models.AddAmount(toBalance, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := &models.Transfer{
+		ID:           uuid.New(),
+		FromWalletID: fromWalletID,
+		ToWalletID:   toWalletID,
+		Currency:     currency,
+		Amount:       amount,
+	}
+
+	legs := []models.WalletOperation{
+		{
+			ID:            uuid.New(),
+			WalletID:      fromWalletID,
+			Operation:     models.OperationTypeTransferOut,
+			Currency:      currency,
+			Amount:        amount,
+			BalanceBefore: fromBalance,
+			BalanceAfter:  fromBalance - amount,
+			TransferID:    &transfer.ID,
+		},
+		{
+			ID:            uuid.New(),
+			WalletID:      toWalletID,
+			Operation:     models.OperationTypeTransferIn,
+			Currency:      currency,
+			Amount:        amount,
+			BalanceBefore: toBalance,
+			BalanceAfter:  toBalanceAfter,
+			TransferID:    &transfer.ID,
+		},
+	}
+
+	for i := range legs {
+		if err := r.setBalance(ctx, tx, legs[i].WalletID, currency, legs[i].BalanceAfter); err != nil {
+			return nil, err
+		}
+		if err := r.insertOperation(ctx, tx, &legs[i]); err != nil {
+			return nil, err
+		}
+	}
+	transfer.CreatedAt = legs[len(legs)-1].CreatedAt
+
+	return transfer, commit(ctx, tx)
+}
+
+// lockWallet takes the row lock on the wallet itself, which serializes all
+// balance and status changes of the wallet regardless of currency.
+func (r *WalletRepository) lockWallet(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (models.WalletStatus, error) {
+	ctx, span := tracer.Start(ctx, "WalletRepository.lockWallet",
+		trace.WithAttributes(tracing.AttrWalletID.String(walletID.String())))
+	defer span.End()
+
+	start := time.Now()
+	var status models.WalletStatus
+	err := tx.QueryRowContext(ctx, "SELECT status FROM wallets WHERE id = $1 FOR UPDATE", walletID).Scan(&status)
+	if err != nil {
+		tracing.RecordError(span, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", models.ErrWalletNotFound
+		}
+		return "", fmt.Errorf("failed to lock wallet: %w", err)
+	}
+	r.logger.LogAttrs(ctx, slog.LevelDebug, "wallet lock acquired",
+		slog.String("wallet_id", walletID.String()), slog.Duration("wait", time.Since(start)))
+
+	return status, nil
+}
+
+func (r *WalletRepository) getBalanceForUpdate(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, currency models.Currency) (int64, error) {
+	var balance int64
+	err := tx.QueryRowContext(ctx, "SELECT balance FROM wallet_balances WHERE wallet_id = $1 AND currency = $2 FOR UPDATE", walletID, currency).Scan(&balance)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// setBalance relies on the wallet_balances CHECK as a last line of defence: a
+// balance may only drop below zero within the overdraft an admin reversal
+// allowed, and that allowance shrinks as the balance recovers.
+func (r *WalletRepository) setBalance(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, currency models.Currency, balance int64) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO wallet_balances (wallet_id, currency, balance) VALUES ($1, $2, $3)
+		ON CONFLICT (wallet_id, currency) DO UPDATE SET balance = EXCLUDED.balance,
+			overdraft = LEAST(wallet_balances.overdraft, GREATEST(-EXCLUDED.balance, 0)), updated_at = NOW()`,
+		walletID, currency, balance)
+	if err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE wallets SET updated_at = NOW() WHERE id = $1", walletID)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return false, err
+		return err
 	}
 
 	if rowsAffected == 0 {
-		return false, fmt.Errorf("failed to update wallet")
+		return fmt.Errorf("failed to update wallet")
+	}
+
+	return nil
+}
+
+func (r *WalletRepository) insertOperation(ctx context.Context, tx *sql.Tx, op *models.WalletOperation) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO wallet_operations (id, wallet_id, operation_type, currency, amount, balance_before, balance_after, transfer_id, hold_id, reversal_of, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, clock_timestamp()) RETURNING seq, created_at",
+		op.ID, op.WalletID, op.Operation, op.Currency, op.Amount, op.BalanceBefore, op.BalanceAfter, op.TransferID, op.HoldID, op.ReversalOf).
+		Scan(&op.Seq, &op.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record operation: %w", err)
+	}
+
+	return r.insertEvent(ctx, tx, op)
+}
+
+const operationColumns = "id, seq, wallet_id, operation_type, currency, amount, balance_before, balance_after, transfer_id, hold_id, reversal_of, created_at"
+
+func scanOperation(row rowScanner) (*models.WalletOperation, error) {
+	var op models.WalletOperation
+	err := row.Scan(&op.ID, &op.Seq, &op.WalletID, &op.Operation, &op.Currency, &op.Amount, &op.BalanceBefore, &op.BalanceAfter,
+		&op.TransferID, &op.HoldID, &op.ReversalOf, &op.CreatedAt)
+	if err != nil {
+		return nil, err
 	}
 
-	return true, tx.Commit()
+	return &op, nil
 }
 
 func (r *WalletRepository) CreateWallet(ctx context.Context, walletID uuid.UUID) (bool, error) {
-	result, err := r.db.ExecContext(ctx, "INSERT INTO wallets (id, balance, created_at) VALUES ($1, $2, NOW()) ON CONFLICT (id) DO NOTHING", walletID, 0)
+	result, err := r.db.ExecContext(ctx, "INSERT INTO wallets (id, created_at) VALUES ($1, NOW()) ON CONFLICT (id) DO NOTHING", walletID)
 	if err != nil {
 		return false, fmt.Errorf("failed to create wallet: %w", err)
 	}
@@ -99,3 +394,67 @@ func (r *WalletRepository) CreateWallet(ctx context.Context, walletID uuid.UUID)
 
 	return rowsAffected > 0, nil
 }
+
+func (r *WalletRepository) ListOperations(ctx context.Context, walletID uuid.UUID, filter models.OperationFilter) ([]models.WalletOperation, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM wallets WHERE id = $1)", walletID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check wallet: %w", err)
+	}
+	if !exists {
+		return nil, models.ErrWalletNotFound
+	}
+
+	conditions := []string{"wallet_id = $1"}
+	args := []any{walletID}
+
+	if filter.BeforeSeq > 0 {
+		args = append(args, filter.BeforeSeq)
+		conditions = append(conditions, fmt.Sprintf("seq < $%d", len(args)))
+	}
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		conditions = append(conditions, fmt.Sprintf("currency = $%d", len(args)))
+	}
+	if len(filter.Types) > 0 {
+		placeholders := make([]string, 0, len(filter.Types))
+		for _, t := range filter.Types {
+			args = append(args, t)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		conditions = append(conditions, fmt.Sprintf("operation_type IN (%s)", strings.Join(placeholders, ", ")))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	args = append(args, filter.Limit)
+
+	query := fmt.Sprintf(
+		"SELECT "+operationColumns+" FROM wallet_operations WHERE %s ORDER BY seq DESC LIMIT $%d",
+		strings.Join(conditions, " AND "), len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer rows.Close()
+
+	operations := make([]models.WalletOperation, 0, filter.Limit)
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan operation: %w", err)
+		}
+		operations = append(operations, *op)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+
+	return operations, nil
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+type WebhookInterface interface {
+	CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (*models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, principal string) ([]models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, id uuid.UUID, url string, eventTypes []string) (*models.WebhookSubscription, error)
+	RotateSecret(ctx context.Context, id uuid.UUID, secret string) (*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status models.DeliveryStatus, limit int) ([]models.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+const subscriptionColumns = "id, principal, all_wallets, url, event_types, created_at, updated_at"
+
+const deliveryColumns = "id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at"
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	created, err := scanSubscription(r.db.QueryRowContext(ctx,
+		"INSERT INTO webhook_subscriptions (id, principal, all_wallets, url, event_types, secret) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+subscriptionColumns,
+		uuid.New(), sub.Principal, sub.AllWallets, sub.URL, pq.Array(sub.EventTypes), sub.Secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	sub, err := scanSubscription(r.db.QueryRowContext(ctx,
+		"SELECT "+subscriptionColumns+" FROM webhook_subscriptions WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+// ListSubscriptions returns the subscriptions of principal, or all of them
+// when principal is empty.
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, principal string) ([]models.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+subscriptionColumns+" FROM webhook_subscriptions WHERE $1 = '' OR principal = $1 ORDER BY created_at", principal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, id uuid.UUID, url string, eventTypes []string) (*models.WebhookSubscription, error) {
+	sub, err := scanSubscription(r.db.QueryRowContext(ctx,
+		"UPDATE webhook_subscriptions SET url = $2, event_types = $3, updated_at = NOW() WHERE id = $1 RETURNING "+subscriptionColumns,
+		id, url, pq.Array(eventTypes)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+func (r *WebhookRepository) RotateSecret(ctx context.Context, id uuid.UUID, secret string) (*models.WebhookSubscription, error) {
+	sub, err := scanSubscription(r.db.QueryRowContext(ctx,
+		"UPDATE webhook_subscriptions SET secret = $2, updated_at = NOW() WHERE id = $1 RETURNING "+subscriptionColumns,
+		id, secret))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	sub.Secret = secret
+
+	return sub, nil
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return models.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the newest deliveries of a subscription, optionally
+// only those in status.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status models.DeliveryStatus, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE subscription_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC LIMIT $3",
+		subscriptionID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery puts a delivery back in the queue with a fresh set of
+// attempts, e.g. to redrive a dead-lettered one.
+func (r *WebhookRepository) RetryDelivery(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx,
+		"UPDATE webhook_deliveries SET status = 'PENDING', attempts = 0, next_attempt_at = NOW() WHERE id = $1 AND subscription_id = $2 RETURNING "+deliveryColumns,
+		deliveryID, subscriptionID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to retry webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// EnqueueDeliveries queues an event for every subscription that wants its
+// type and covers its wallet, either through wallet ownership or because
+// the subscription covers all wallets. Queuing the same event twice is a
+// no-op, so the outbox may redeliver it safely. It implements outbox.Sink
+// through webhook.Fanout.
+func (r *WebhookRepository) EnqueueDeliveries(ctx context.Context, event models.Event) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload)
+		SELECT gen_random_uuid(), s.id, $1, $3, $4
+		FROM webhook_subscriptions s
+		WHERE (cardinality(s.event_types) = 0 OR $3 = ANY(s.event_types))
+			AND (s.all_wallets OR EXISTS (
+				SELECT 1 FROM wallet_owners o WHERE o.wallet_id = $2 AND o.principal = s.principal
+			))
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		event.ID, event.WalletID, event.Type, []byte(event.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// ClaimDeliveries picks up to limit due deliveries and pushes their next
+// attempt lease into the future, so other replicas skip them while they are
+// being sent. A delivery whose sender dies is picked up again once the
+// lease expires.
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.attempts, d.created_at, s.url, s.secret`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.PendingDelivery
+	for rows.Next() {
+		var d models.PendingDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Status = models.DeliveryStatusPending
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, deliveryID uuid.UUID, attempt models.DeliveryAttempt) error {
+	status := models.DeliveryStatusPending
+	switch {
+	case attempt.Delivered:
+		status = models.DeliveryStatusDelivered
+	case attempt.Dead:
+		status = models.DeliveryStatusDead
+	}
+
+	var statusCode *int
+	if attempt.StatusCode != 0 {
+		statusCode = &attempt.StatusCode
+	}
+	var lastError *string
+	if attempt.Error != "" {
+		lastError = &attempt.Error
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET
+			status = $2,
+			attempts = attempts + 1,
+			last_status_code = $3,
+			last_error = $4,
+			next_attempt_at = NOW() + make_interval(secs => $5),
+			delivered_at = CASE WHEN $2 = 'DELIVERED' THEN NOW() END
+		WHERE id = $1`,
+		deliveryID, status, statusCode, lastError, attempt.RetryAfter.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := row.Scan(&sub.ID, &sub.Principal, &sub.AllWallets, &sub.URL, pq.Array(&sub.EventTypes), &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	if err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/auth"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/repository"
+)
+
+type APIKeyService struct {
+	apiKeyRepo repository.APIKeyInterface
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyInterface) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// CreateAPIKey issues a key for the principal. The returned key is the only
+// place the plaintext ever appears.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, name, principal string, role models.Role) (*models.APIKey, error) {
+	if !role.IsValid() {
+		return nil, models.ErrInvalidRole
+	}
+
+	name, principal = strings.TrimSpace(name), strings.TrimSpace(principal)
+	if name == "" || principal == "" {
+		return nil, fmt.Errorf("%w: name and principal are required", models.ErrInvalidPrincipal)
+	}
+
+	key, keyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	created, err := s.apiKeyRepo.CreateAPIKey(ctx, models.APIKey{Name: name, Principal: principal, Role: role}, keyHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+	created.Key = key
+
+	return created, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	if err := s.apiKeyRepo.RevokeAPIKey(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return nil
+}
+
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*models.Principal, error) {
+	apiKey, err := s.apiKeyRepo.FindAPIKey(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, models.ErrAPIKeyNotFound) {
+			return nil, models.ErrUnauthenticated
+		}
+		return nil, fmt.Errorf("failed to authenticate api key: %w", err)
+	}
+
+	return &models.Principal{ID: apiKey.Principal, Role: apiKey.Role}, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/tracing"
+)
+
+// GetBalanceAt returns the balance of the wallet as of at, computed from the
+// ledger. A nil at means now, which is answered from the stored balance.
+func (s *WalletService) GetBalanceAt(ctx context.Context, walletID uuid.UUID, currency models.Currency, at *time.Time) (*models.BalanceAt, error) {
+	ctx, span := startSpan(ctx, "GetBalanceAt",
+		tracing.AttrWalletID.String(walletID.String()),
+		tracing.AttrCurrency.String(string(currency)))
+	defer span.End()
+
+	if !currency.IsValid() {
+		return nil, models.ErrInvalidCurrency
+	}
+
+	now := time.Now().UTC()
+	if at == nil {
+		balance, err := s.GetBalance(ctx, walletID, currency)
+		if err != nil {
+			return nil, err
+		}
+		return &models.BalanceAt{WalletID: walletID, Currency: currency, Balance: balance, At: now}, nil
+	}
+
+	if at.After(now) {
+		return nil, models.ErrFutureBalanceTime
+	}
+
+	balance, err := s.walletRepo.GetBalanceAt(ctx, walletID, currency, *at)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to get balance at %s: %w", at.Format(time.RFC3339), err)
+	}
+
+	return &models.BalanceAt{WalletID: walletID, Currency: currency, Balance: balance, At: *at}, nil
+}
+
+// SnapshotBalances records a snapshot of every balance that has had at least
+// every operations since its latest snapshot, bounding the ledger scanned by
+// GetBalanceAt.
+func (s *WalletService) SnapshotBalances(ctx context.Context, every int) (int, error) {
+	if every <= 0 {
+		return 0, fmt.Errorf("snapshot interval must be positive, got %d", every)
+	}
+
+	taken, err := s.walletRepo.SnapshotBalances(ctx, every)
+	if err != nil {
+		return taken, fmt.Errorf("failed to snapshot balances: %w", err)
+	}
+
+	return taken, nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ProcessBatch applies a batch of deposits and withdrawals on behalf of the
+// principal. Items are validated and every wallet is authorized before the
+// batch reaches the database; unknown wallets are created for deposits when
+// autoCreate is set, in the batch's transaction. In atomic mode the first rejected item fails the whole
+// batch with a *models.BatchItemError; in best-effort mode the result of each
+// item is returned in request order.
+func (s *WalletService) ProcessBatch(ctx context.Context, principal *models.Principal, mode models.BatchMode, items []models.BatchItem, autoCreate bool) ([]models.BatchResult, error) {
+	ctx, span := startSpan(ctx, "ProcessBatch",
+		attribute.String("batch.mode", string(mode)),
+		attribute.Int("batch.size", len(items)))
+	defer span.End()
+	start := time.Now()
+
+	if !mode.IsValid() {
+		return nil, models.ErrInvalidBatchMode
+	}
+	if len(items) == 0 || len(items) > models.MaxBatchSize {
+		return nil, models.ErrInvalidBatchSize
+	}
+
+	results := make([]models.BatchResult, len(items))
+	rejected := make([]bool, len(items))
+	reject := func(i int, err error) error {
+		if mode == models.BatchModeAtomic {
+			return &models.BatchItemError{Index: i, Err: err}
+		}
+		results[i] = models.BatchResult{Index: i, Err: err}
+		rejected[i] = true
+		return nil
+	}
+
+	for i, item := range items {
+		if err := validateBatchItem(item); err != nil {
+			if err := reject(i, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	deposits := make(map[uuid.UUID]bool)
+	for i, item := range items {
+		if !rejected[i] && item.Operation == models.OperationTypeDeposit {
+			deposits[item.WalletID] = true
+		}
+	}
+
+	walletErrs := make(map[uuid.UUID]error)
+	var newWallets []uuid.UUID
+	for i, item := range items {
+		if rejected[i] {
+			continue
+		}
+		err, seen := walletErrs[item.WalletID]
+		if !seen {
+			var create bool
+			create, err = s.authorizeBatchWallet(ctx, principal, item.WalletID, autoCreate && deposits[item.WalletID])
+			walletErrs[item.WalletID] = err
+			if create {
+				newWallets = append(newWallets, item.WalletID)
+			}
+		}
+		if err != nil {
+			if err := reject(i, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	accepted := make([]models.BatchItem, 0, len(items))
+	for i, item := range items {
+		if !rejected[i] {
+			item.Index = i
+			accepted = append(accepted, item)
+		}
+	}
+
+	var err error
+	if len(accepted) > 0 {
+		var applied []models.BatchResult
+		var owner string
+		if principal != nil && !principal.IsAdmin() {
+			owner = principal.ID
+		}
+		applied, err = s.walletRepo.ApplyBatch(ctx, mode, accepted, newWallets, owner)
+		for _, result := range applied {
+			results[result.Index] = result
+		}
+	}
+
+	s.recordOperation(ctx, "BATCH", start, err,
+		slog.String("mode", string(mode)), slog.Int("items", len(items)), slog.Int("failed", countFailed(results)))
+	if err != nil {
+		var itemErr *models.BatchItemError
+		if errors.As(err, &itemErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to process batch: %w", err)
+	}
+
+	return results, nil
+}
+
+func validateBatchItem(item models.BatchItem) error {
+	if item.WalletID == uuid.Nil {
+		return models.ErrInvalidWalletID
+	}
+	if item.Operation != models.OperationTypeDeposit && item.Operation != models.OperationTypeWithdraw {
+		return fmt.Errorf("%w: %s", models.ErrInvalidOperation, item.Operation)
+	}
+	if !item.Currency.IsValid() {
+		return models.ErrInvalidCurrency
+	}
+	if item.Amount <= 0 {
+		return models.ErrInvalidAmount
+	}
+
+	return nil
+}
+
+// authorizeBatchWallet checks the principal may use the wallet. An unknown
+// wallet is reported for creation when create is set, i.e. when
+// auto-creation is enabled and the batch deposits to the wallet, as for
+// single operations.
+func (s *WalletService) authorizeBatchWallet(ctx context.Context, principal *models.Principal, walletID uuid.UUID, create bool) (bool, error) {
+	err := s.AuthorizeWallet(ctx, principal, walletID)
+	if errors.Is(err, models.ErrWalletNotFound) && create {
+		return true, nil
+	}
+
+	return false, err
+}
+
+func countFailed(results []models.BatchResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/tracing"
+)
+
+const (
+	DefaultHoldTTL = 24 * time.Hour
+	MaxHoldTTL     = 30 * 24 * time.Hour
+)
+
+func (s *WalletService) CreateHold(ctx context.Context, walletID uuid.UUID, currency models.Currency, amount int64, ttl time.Duration) (*models.Hold, error) {
+	ctx, span := startSpan(ctx, "CreateHold",
+		tracing.AttrWalletID.String(walletID.String()),
+		tracing.AttrCurrency.String(string(currency)),
+		tracing.AttrAmount.Int64(amount))
+	defer span.End()
+	start := time.Now()
+
+	if !currency.IsValid() {
+		return nil, models.ErrInvalidCurrency
+	}
+
+	if amount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+
+	if ttl == 0 {
+		ttl = DefaultHoldTTL
+	}
+	if ttl < time.Second || ttl > MaxHoldTTL {
+		return nil, models.ErrInvalidHoldTTL
+	}
+
+	hold, err := s.walletRepo.CreateHold(ctx, walletID, currency, amount, ttl)
+	s.recordOperation(ctx, "HOLD", start, err,
+		slog.String("wallet_id", walletID.String()), slog.String("currency", string(currency)), slog.Int64("amount", amount))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (s *WalletService) GetHold(ctx context.Context, holdID uuid.UUID) (*models.Hold, error) {
+	hold, err := s.walletRepo.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+// CaptureHold captures amount from the hold, or the whole hold when amount is nil.
+func (s *WalletService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount *int64) (*models.Hold, error) {
+	ctx, span := startSpan(ctx, "CaptureHold", tracing.AttrHoldID.String(holdID.String()))
+	defer span.End()
+	start := time.Now()
+
+	var captureAmount int64
+	if amount != nil {
+		captureAmount = *amount
+		if captureAmount <= 0 {
+			return nil, models.ErrInvalidAmount
+		}
+	} else {
+		hold, err := s.GetHold(ctx, holdID)
+		if err != nil {
+			return nil, err
+		}
+		captureAmount = hold.Amount
+	}
+
+	hold, err := s.walletRepo.CaptureHold(ctx, holdID, captureAmount)
+	s.recordOperation(ctx, string(models.OperationTypeCapture), start, err,
+		slog.String("hold_id", holdID.String()), slog.Int64("amount", captureAmount))
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (s *WalletService) ReleaseHold(ctx context.Context, holdID uuid.UUID) (*models.Hold, error) {
+	ctx, span := startSpan(ctx, "ReleaseHold", tracing.AttrHoldID.String(holdID.String()))
+	defer span.End()
+	start := time.Now()
+
+	hold, err := s.walletRepo.ReleaseHold(ctx, holdID)
+	s.recordOperation(ctx, "RELEASE", start, err, slog.String("hold_id", holdID.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to release hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (s *WalletService) ExpireHolds(ctx context.Context) (int64, error) {
+	expired, err := s.walletRepo.ExpireHolds(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire holds: %w", err)
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+// GetLimits returns the limits in effect for the wallet: its own policy if it has one,
+// otherwise the default policy. A nil walletID asks for the default policy only.
+func (s *WalletService) GetLimits(ctx context.Context, walletID *uuid.UUID, currency models.Currency) (*models.WithdrawalLimits, error) {
+	if !currency.IsValid() {
+		return nil, models.ErrInvalidCurrency
+	}
+
+	if walletID != nil {
+		if _, err := s.GetWallet(ctx, *walletID); err != nil {
+			return nil, err
+		}
+
+		limits, err := s.walletRepo.GetLimits(ctx, walletID, currency)
+		if err == nil {
+			return limits, nil
+		}
+		if !errors.Is(err, models.ErrLimitsNotFound) {
+			return nil, fmt.Errorf("failed to get limits: %w", err)
+		}
+	}
+
+	limits, err := s.walletRepo.GetLimits(ctx, nil, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+func (s *WalletService) SetLimits(ctx context.Context, limits models.WithdrawalLimits) (*models.WithdrawalLimits, error) {
+	if !limits.Currency.IsValid() {
+		return nil, models.ErrInvalidCurrency
+	}
+
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+
+	if limits.WalletID != nil {
+		if _, err := s.GetWallet(ctx, *limits.WalletID); err != nil {
+			return nil, err
+		}
+	}
+
+	saved, err := s.walletRepo.SetLimits(ctx, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set limits: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (s *WalletService) DeleteLimits(ctx context.Context, walletID *uuid.UUID, currency models.Currency) error {
+	if !currency.IsValid() {
+		return models.ErrInvalidCurrency
+	}
+
+	if err := s.walletRepo.DeleteLimits(ctx, walletID, currency); err != nil {
+		return fmt.Errorf("failed to delete limits: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+// AuthorizeWallet checks that the principal may operate on the wallet: admins
+// may access any wallet, other principals only wallets granted by their
+// credential or recorded as theirs. An unknown wallet yields ErrWalletNotFound
+// so callers can decide whether to create it.
+func (s *WalletService) AuthorizeWallet(ctx context.Context, principal *models.Principal, walletID uuid.UUID) error {
+	if principal == nil {
+		return models.ErrUnauthenticated
+	}
+	if principal.IsAdmin() || principal.HasWalletGrant(walletID) {
+		return nil
+	}
+
+	owner, err := s.walletRepo.IsWalletOwner(ctx, walletID, principal.ID)
+	if err != nil {
+		return fmt.Errorf("failed to authorize wallet: %w", err)
+	}
+	if owner {
+		return nil
+	}
+
+	if _, err := s.GetWallet(ctx, walletID); err != nil {
+		return err
+	}
+
+	return models.ErrForbidden
+}
+
+func (s *WalletService) AddWalletOwner(ctx context.Context, walletID uuid.UUID, principal string) error {
+	if principal == "" {
+		return fmt.Errorf("%w: principal is required", models.ErrInvalidPrincipal)
+	}
+
+	if _, err := s.GetWallet(ctx, walletID); err != nil {
+		return err
+	}
+
+	if err := s.walletRepo.AddWalletOwner(ctx, walletID, principal); err != nil {
+		return fmt.Errorf("failed to add wallet owner: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOwnedWallet creates the wallet and records the principal as its owner.
+// If the wallet already exists the principal must already be allowed to use it.
+func (s *WalletService) CreateOwnedWallet(ctx context.Context, principal *models.Principal, walletID uuid.UUID) (bool, error) {
+	if principal == nil {
+		return false, models.ErrUnauthenticated
+	}
+
+	owner := principal.ID
+	if principal.IsAdmin() {
+		owner = ""
+	}
+
+	created, err := s.walletRepo.CreateOwnedWallet(ctx, walletID, owner)
+	if err != nil {
+		return false, fmt.Errorf("failed to create wallet: %w", err)
+	}
+	if !created {
+		if err := s.AuthorizeWallet(ctx, principal, walletID); err != nil && !errors.Is(err, models.ErrWalletNotFound) {
+			return false, err
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/metrics"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Reconcile recomputes every stored balance from the ledger and reports the
+// balances that differ. With repair set, each of them is reset to the ledger
+// balance with an audited adjustment made on behalf of actor. The report is
+// saved even when some repairs fail.
+func (s *WalletService) Reconcile(ctx context.Context, repair bool, actor string) (*models.ReconciliationReport, error) {
+	ctx, span := startSpan(ctx, "Reconcile", attribute.Bool("reconcile.repair", repair))
+	defer span.End()
+
+	actor = strings.TrimSpace(actor)
+	if repair && actor == "" {
+		return nil, fmt.Errorf("%w: actor is required to repair balances", models.ErrInvalidPrincipal)
+	}
+
+	var report *models.ReconciliationReport
+	err := s.walletRepo.WithReconcileLock(ctx, func(ctx context.Context) error {
+		var err error
+		report, err = s.reconcile(ctx, repair, actor)
+		return err
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to reconcile balances: %w", err)
+	}
+
+	unresolved := len(report.Mismatches) - report.Repaired
+	metrics.BalanceMismatches.Set(float64(unresolved))
+
+	level := slog.LevelInfo
+	if unresolved > 0 {
+		level = slog.LevelWarn
+	}
+	s.logger.LogAttrs(ctx, level, "reconciliation finished",
+		slog.String("run_id", report.ID.String()),
+		slog.Int("checked", report.BalancesChecked),
+		slog.Int("mismatches", len(report.Mismatches)),
+		slog.Int("repaired", report.Repaired),
+		slog.Duration("duration", report.FinishedAt.Sub(report.StartedAt)))
+
+	return report, nil
+}
+
+func (s *WalletService) reconcile(ctx context.Context, repair bool, actor string) (*models.ReconciliationReport, error) {
+	report := &models.ReconciliationReport{
+		ID:         uuid.New(),
+		StartedAt:  time.Now().UTC(),
+		Mismatches: []models.BalanceMismatch{},
+	}
+
+	checked, mismatches, err := s.walletRepo.FindBalanceMismatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.BalancesChecked = checked
+
+	for _, mismatch := range mismatches {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "balance differs from ledger",
+			slog.String("wallet_id", mismatch.WalletID.String()),
+			slog.String("currency", string(mismatch.Currency)),
+			slog.Int64("stored", mismatch.StoredBalance),
+			slog.Int64("ledger", mismatch.LedgerBalance))
+
+		if repair {
+			// A nil adjustment means the balance already matches the ledger again.
+			if _, err := s.walletRepo.RepairBalance(ctx, report.ID, mismatch.WalletID, mismatch.Currency, actor); err != nil {
+				mismatch.RepairError = err.Error()
+			} else {
+				mismatch.Repaired = true
+				report.Repaired++
+			}
+		}
+		report.Mismatches = append(report.Mismatches, mismatch)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	if err := s.walletRepo.SaveReconciliationRun(ctx, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
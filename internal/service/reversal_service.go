@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func (s *WalletService) GetOperation(ctx context.Context, operationID uuid.UUID) (*models.WalletOperation, error) {
+	op, err := s.walletRepo.GetOperation(ctx, operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	return op, nil
+}
+
+// ReverseOperation reverses the operation, in full when amount is nil.
+// Reversals are admin-only: a client reversing its own withdrawal would be
+// crediting money that already left the system.
+func (s *WalletService) ReverseOperation(ctx context.Context, principal *models.Principal, operationID uuid.UUID, amount *int64, allowNegative bool) (*models.WalletOperation, error) {
+	ctx, span := startSpan(ctx, "ReverseOperation",
+		attribute.String("wallet.operation_id", operationID.String()),
+		attribute.Bool("wallet.allow_negative", allowNegative))
+	defer span.End()
+	start := time.Now()
+
+	if principal == nil || !principal.IsAdmin() {
+		return nil, fmt.Errorf("%w: only admins may reverse operations", models.ErrForbidden)
+	}
+
+	if amount != nil && *amount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+
+	reversal, err := s.walletRepo.ReverseOperation(ctx, operationID, amount, allowNegative)
+	attrs := []slog.Attr{slog.String("reversal_of", operationID.String()), slog.Bool("allow_negative", allowNegative)}
+	if reversal != nil {
+		attrs = append(attrs, slog.String("wallet_id", reversal.WalletID.String()),
+			slog.String("currency", string(reversal.Currency)), slog.Int64("amount", reversal.Amount))
+	}
+	s.recordOperation(ctx, string(models.OperationTypeReversal), start, err, attrs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reverse operation: %w", err)
+	}
+
+	return reversal, nil
+}
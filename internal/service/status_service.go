@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+)
+
+func (s *WalletService) SetWalletStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus, reason, actor string) (*models.WalletStatusChange, error) {
+	if !status.IsValid() {
+		return nil, models.ErrInvalidStatus
+	}
+
+	reason, actor = strings.TrimSpace(reason), strings.TrimSpace(actor)
+	if reason == "" || actor == "" {
+		return nil, fmt.Errorf("%w: reason and actor are required", models.ErrInvalidStatus)
+	}
+
+	change, err := s.walletRepo.SetWalletStatus(ctx, walletID, status, reason, actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set wallet status: %w", err)
+	}
+
+	return change, nil
+}
+
+func (s *WalletService) ListStatusChanges(ctx context.Context, walletID uuid.UUID) ([]models.WalletStatusChange, error) {
+	if _, err := s.GetWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.walletRepo.ListStatusChanges(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status changes: %w", err)
+	}
+
+	return changes, nil
+}
@@ -2,25 +2,48 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/metrics"
 	"github.com/itk/wallet/internal/models"
 	"github.com/itk/wallet/internal/repository"
+	"github.com/itk/wallet/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/itk/wallet/internal/service")
+
+const (
+	DefaultOperationsLimit = 50
+	MaxOperationsLimit     = 200
 )
 
 type WalletService struct {
 	walletRepo repository.WalletInterface
+	logger     *slog.Logger
 }
 
-func NewWalletService(walletRepo repository.WalletInterface) *WalletService {
+func NewWalletService(walletRepo repository.WalletInterface, logger *slog.Logger) *WalletService {
 	return &WalletService{
 		walletRepo: walletRepo,
+		logger:     logger,
 	}
 }
 
-func (s *WalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (int, error) {
-	balance, err := s.walletRepo.GetBalance(ctx, walletID)
+func (s *WalletService) GetBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency) (int64, error) {
+	if !currency.IsValid() {
+		return 0, models.ErrInvalidCurrency
+	}
+
+	balance, err := s.walletRepo.GetBalance(ctx, walletID, currency)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get wallets balance: %w", err)
 	}
@@ -28,16 +51,56 @@ func (s *WalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (int
 	return balance, nil
 }
 
-func (s *WalletService) UpdateBalance(ctx context.Context, walletID uuid.UUID, operationType models.OperationType, amount int) (bool, error) {
+func (s *WalletService) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	ctx, span := startSpan(ctx, "GetWallet", tracing.AttrWalletID.String(walletID.String()))
+	defer span.End()
+
+	wallet, err := s.walletRepo.GetWallet(ctx, walletID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+func (s *WalletService) UpdateBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error) {
+	ctx, span := startSpan(ctx, "UpdateBalance",
+		tracing.AttrWalletID.String(walletID.String()),
+		tracing.AttrOperation.String(string(operationType)),
+		tracing.AttrCurrency.String(string(currency)),
+		tracing.AttrAmount.Int64(amount))
+	defer span.End()
+	start := time.Now()
+
+	ok, err := s.updateBalance(ctx, walletID, currency, operationType, amount)
+	operation := string(operationType)
+	if errors.Is(err, models.ErrInvalidOperation) {
+		// Keep the metric label bounded whatever the caller sent.
+		operation = "INVALID"
+	}
+	s.recordOperation(ctx, operation, start, err,
+		slog.String("wallet_id", walletID.String()), slog.String("currency", string(currency)), slog.Int64("amount", amount))
+
+	return ok, err
+}
+
+// updateBalance validates the operation and applies it. Rejected requests
+// return here too, so UpdateBalance counts and logs them like failed ones.
+func (s *WalletService) updateBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error) {
 	if operationType != models.OperationTypeDeposit && operationType != models.OperationTypeWithdraw {
-		return false, fmt.Errorf("invalid operationType: %s", operationType)
+		return false, fmt.Errorf("%w: %s", models.ErrInvalidOperation, operationType)
+	}
+
+	if !currency.IsValid() {
+		return false, models.ErrInvalidCurrency
 	}
 
 	if amount <= 0 {
-		return false, fmt.Errorf("amount must be greater than zero")
+		return false, models.ErrInvalidAmount
 	}
 
-	ok, err := s.walletRepo.UpdateBalance(ctx, walletID, operationType, amount)
+	ok, err := s.walletRepo.UpdateBalance(ctx, walletID, currency, operationType, amount)
 	if err != nil {
 		return false, fmt.Errorf("failed to update balance: %w", err)
 	}
@@ -53,3 +116,132 @@ func (s *WalletService) CreateWallet(ctx context.Context, walletID uuid.UUID) (b
 
 	return ok, nil
 }
+
+func (s *WalletService) OpenWallet(ctx context.Context, principal *models.Principal, walletID uuid.UUID) (*models.Wallet, error) {
+	created, err := s.CreateOwnedWallet(ctx, principal, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, models.ErrWalletExists
+	}
+
+	return s.GetWallet(ctx, walletID)
+}
+
+func (s *WalletService) Transfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, currency models.Currency, amount int64) (*models.Transfer, error) {
+	ctx, span := startSpan(ctx, "Transfer",
+		tracing.AttrWalletID.String(fromWalletID.String()),
+		attribute.String("wallet.to_id", toWalletID.String()),
+		tracing.AttrCurrency.String(string(currency)),
+		tracing.AttrAmount.Int64(amount))
+	defer span.End()
+	start := time.Now()
+
+	if fromWalletID == toWalletID {
+		return nil, models.ErrSameWallet
+	}
+
+	if !currency.IsValid() {
+		return nil, models.ErrInvalidCurrency
+	}
+
+	if amount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+
+	transfer, err := s.walletRepo.Transfer(ctx, fromWalletID, toWalletID, currency, amount)
+	s.recordOperation(ctx, "TRANSFER", start, err,
+		slog.String("wallet_id", fromWalletID.String()), slog.String("to_wallet_id", toWalletID.String()),
+		slog.String("currency", string(currency)), slog.Int64("amount", amount))
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (s *WalletService) ListOperations(ctx context.Context, walletID uuid.UUID, filter models.OperationFilter, cursor string) ([]models.WalletOperation, string, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultOperationsLimit
+	}
+	if filter.Limit > MaxOperationsLimit {
+		filter.Limit = MaxOperationsLimit
+	}
+
+	if filter.Currency != "" && !filter.Currency.IsValid() {
+		return nil, "", models.ErrInvalidCurrency
+	}
+
+	if cursor != "" {
+		seq, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.BeforeSeq = seq
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, "", models.ErrInvalidTimeRange
+	}
+
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	operations, err := s.walletRepo.ListOperations(ctx, walletID, filter)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list operations: %w", err)
+	}
+
+	var nextCursor string
+	if len(operations) > limit {
+		operations = operations[:limit]
+		nextCursor = encodeCursor(operations[limit-1].Seq)
+	}
+
+	return operations, nextCursor, nil
+}
+
+func encodeCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, models.ErrInvalidCursor
+	}
+
+	seq, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || seq <= 0 {
+		return 0, models.ErrInvalidCursor
+	}
+
+	return seq, nil
+}
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "WalletService."+name, trace.WithAttributes(attrs...))
+}
+
+// recordOperation counts, traces and logs the outcome of a balance-changing
+// operation, one log line per operation.
+func (s *WalletService) recordOperation(ctx context.Context, operation string, start time.Time, err error, attrs ...slog.Attr) {
+	outcome := metrics.Outcome(err)
+	metrics.ObserveOperation(operation, err)
+
+	level := slog.LevelInfo
+	attrs = append(attrs,
+		slog.String("operation", operation),
+		slog.String("outcome", outcome),
+		slog.Duration("duration", time.Since(start)))
+	if err != nil {
+		tracing.RecordError(trace.SpanFromContext(ctx), err)
+		attrs = append(attrs, slog.String("error", err.Error()))
+		if outcome == "error" {
+			level = slog.LevelError
+		}
+	}
+
+	s.logger.LogAttrs(ctx, level, "wallet operation", attrs...)
+}
@@ -1,31 +1,63 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
-	"strings"
+	"log/slog"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/logging"
 	"github.com/itk/wallet/internal/models"
 )
 
 type MockWalletRepository struct {
-	GetBalanceFunc    func(ctx context.Context, walletID uuid.UUID) (int, error)
-	UpdateBalanceFunc func(ctx context.Context, walletID uuid.UUID, operationType models.OperationType, amount int) (bool, error)
-	CreateWalletFunc  func(ctx context.Context, walletID uuid.UUID) (bool, error)
+	GetBalanceFunc     func(ctx context.Context, walletID uuid.UUID, currency models.Currency) (int64, error)
+	GetWalletFunc      func(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error)
+	UpdateBalanceFunc  func(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error)
+	CreateWalletFunc   func(ctx context.Context, walletID uuid.UUID) (bool, error)
+	ListOperationsFunc func(ctx context.Context, walletID uuid.UUID, filter models.OperationFilter) ([]models.WalletOperation, error)
+	TransferFunc       func(ctx context.Context, fromWalletID, toWalletID uuid.UUID, currency models.Currency, amount int64) (*models.Transfer, error)
+	CreateHoldFunc     func(ctx context.Context, walletID uuid.UUID, currency models.Currency, amount int64, ttl time.Duration) (*models.Hold, error)
+	GetHoldFunc        func(ctx context.Context, holdID uuid.UUID) (*models.Hold, error)
+	CaptureHoldFunc    func(ctx context.Context, holdID uuid.UUID, amount int64) (*models.Hold, error)
+	ReleaseHoldFunc    func(ctx context.Context, holdID uuid.UUID) (*models.Hold, error)
+	ExpireHoldsFunc    func(ctx context.Context) (int64, error)
+	GetLimitsFunc      func(ctx context.Context, walletID *uuid.UUID, currency models.Currency) (*models.WithdrawalLimits, error)
+	SetLimitsFunc      func(ctx context.Context, limits models.WithdrawalLimits) (*models.WithdrawalLimits, error)
+	DeleteLimitsFunc   func(ctx context.Context, walletID *uuid.UUID, currency models.Currency) error
+	SetStatusFunc      func(ctx context.Context, walletID uuid.UUID, status models.WalletStatus, reason, actor string) (*models.WalletStatusChange, error)
+	IsWalletOwnerFunc  func(ctx context.Context, walletID uuid.UUID, principal string) (bool, error)
+	ApplyBatchFunc     func(ctx context.Context, mode models.BatchMode, items []models.BatchItem, newWallets []uuid.UUID, owner string) ([]models.BatchResult, error)
+	FindMismatchesFunc func(ctx context.Context) (int, []models.BalanceMismatch, error)
+	RepairBalanceFunc  func(ctx context.Context, runID, walletID uuid.UUID, currency models.Currency, actor string) (*models.BalanceAdjustment, error)
+	GetBalanceAtFunc   func(ctx context.Context, walletID uuid.UUID, currency models.Currency, at time.Time) (int64, error)
+	ReverseFunc        func(ctx context.Context, operationID uuid.UUID, amount *int64, allowNegative bool) (*models.WalletOperation, error)
+	owners             []string
+	runs               []*models.ReconciliationReport
 }
 
-func (m *MockWalletRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int, error) {
+func (m *MockWalletRepository) GetBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency) (int64, error) {
 	if m.GetBalanceFunc != nil {
-		return m.GetBalanceFunc(ctx, walletID)
+		return m.GetBalanceFunc(ctx, walletID, currency)
 	}
 	return 0, nil
 }
 
-func (m *MockWalletRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, operationType models.OperationType, amount int) (bool, error) {
+func (m *MockWalletRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	if m.GetWalletFunc != nil {
+		return m.GetWalletFunc(ctx, walletID)
+	}
+	return &models.Wallet{ID: walletID}, nil
+}
+
+func (m *MockWalletRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error) {
 	if m.UpdateBalanceFunc != nil {
-		return m.UpdateBalanceFunc(ctx, walletID, operationType, amount)
+		return m.UpdateBalanceFunc(ctx, walletID, currency, operationType, amount)
 	}
 	return true, nil
 }
@@ -37,19 +69,179 @@ func (m *MockWalletRepository) CreateWallet(ctx context.Context, walletID uuid.U
 	return true, nil
 }
 
+func (m *MockWalletRepository) ListOperations(ctx context.Context, walletID uuid.UUID, filter models.OperationFilter) ([]models.WalletOperation, error) {
+	if m.ListOperationsFunc != nil {
+		return m.ListOperationsFunc(ctx, walletID, filter)
+	}
+	return nil, nil
+}
+
+func (m *MockWalletRepository) Transfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, currency models.Currency, amount int64) (*models.Transfer, error) {
+	if m.TransferFunc != nil {
+		return m.TransferFunc(ctx, fromWalletID, toWalletID, currency, amount)
+	}
+	return &models.Transfer{ID: uuid.New(), FromWalletID: fromWalletID, ToWalletID: toWalletID, Currency: currency, Amount: amount}, nil
+}
+
+func (m *MockWalletRepository) CreateHold(ctx context.Context, walletID uuid.UUID, currency models.Currency, amount int64, ttl time.Duration) (*models.Hold, error) {
+	if m.CreateHoldFunc != nil {
+		return m.CreateHoldFunc(ctx, walletID, currency, amount, ttl)
+	}
+	return &models.Hold{ID: uuid.New(), WalletID: walletID, Currency: currency, Amount: amount, Status: models.HoldStatusActive}, nil
+}
+
+func (m *MockWalletRepository) GetHold(ctx context.Context, holdID uuid.UUID) (*models.Hold, error) {
+	if m.GetHoldFunc != nil {
+		return m.GetHoldFunc(ctx, holdID)
+	}
+	return &models.Hold{ID: holdID, Status: models.HoldStatusActive}, nil
+}
+
+func (m *MockWalletRepository) CaptureHold(ctx context.Context, holdID uuid.UUID, amount int64) (*models.Hold, error) {
+	if m.CaptureHoldFunc != nil {
+		return m.CaptureHoldFunc(ctx, holdID, amount)
+	}
+	return &models.Hold{ID: holdID, CapturedAmount: amount, Status: models.HoldStatusCaptured}, nil
+}
+
+func (m *MockWalletRepository) ReleaseHold(ctx context.Context, holdID uuid.UUID) (*models.Hold, error) {
+	if m.ReleaseHoldFunc != nil {
+		return m.ReleaseHoldFunc(ctx, holdID)
+	}
+	return &models.Hold{ID: holdID, Status: models.HoldStatusReleased}, nil
+}
+
+func (m *MockWalletRepository) ExpireHolds(ctx context.Context) (int64, error) {
+	if m.ExpireHoldsFunc != nil {
+		return m.ExpireHoldsFunc(ctx)
+	}
+	return 0, nil
+}
+
+func (m *MockWalletRepository) GetLimits(ctx context.Context, walletID *uuid.UUID, currency models.Currency) (*models.WithdrawalLimits, error) {
+	if m.GetLimitsFunc != nil {
+		return m.GetLimitsFunc(ctx, walletID, currency)
+	}
+	return nil, models.ErrLimitsNotFound
+}
+
+func (m *MockWalletRepository) SetLimits(ctx context.Context, limits models.WithdrawalLimits) (*models.WithdrawalLimits, error) {
+	if m.SetLimitsFunc != nil {
+		return m.SetLimitsFunc(ctx, limits)
+	}
+	return &limits, nil
+}
+
+func (m *MockWalletRepository) DeleteLimits(ctx context.Context, walletID *uuid.UUID, currency models.Currency) error {
+	if m.DeleteLimitsFunc != nil {
+		return m.DeleteLimitsFunc(ctx, walletID, currency)
+	}
+	return nil
+}
+
+func (m *MockWalletRepository) SetWalletStatus(ctx context.Context, walletID uuid.UUID, status models.WalletStatus, reason, actor string) (*models.WalletStatusChange, error) {
+	if m.SetStatusFunc != nil {
+		return m.SetStatusFunc(ctx, walletID, status, reason, actor)
+	}
+	return &models.WalletStatusChange{WalletID: walletID, FromStatus: models.WalletStatusActive, ToStatus: status, Reason: reason, Actor: actor}, nil
+}
+
+func (m *MockWalletRepository) CreateOwnedWallet(ctx context.Context, walletID uuid.UUID, owner string) (bool, error) {
+	created, err := m.CreateWallet(ctx, walletID)
+	if err != nil || !created {
+		return created, err
+	}
+	if owner != "" {
+		m.owners = append(m.owners, owner)
+	}
+	return true, nil
+}
+
+func (m *MockWalletRepository) AddWalletOwner(ctx context.Context, walletID uuid.UUID, principal string) error {
+	m.owners = append(m.owners, principal)
+	return nil
+}
+
+func (m *MockWalletRepository) IsWalletOwner(ctx context.Context, walletID uuid.UUID, principal string) (bool, error) {
+	if m.IsWalletOwnerFunc != nil {
+		return m.IsWalletOwnerFunc(ctx, walletID, principal)
+	}
+	return false, nil
+}
+
+func (m *MockWalletRepository) ApplyBatch(ctx context.Context, mode models.BatchMode, items []models.BatchItem, newWallets []uuid.UUID, owner string) ([]models.BatchResult, error) {
+	if m.ApplyBatchFunc != nil {
+		return m.ApplyBatchFunc(ctx, mode, items, newWallets, owner)
+	}
+	results := make([]models.BatchResult, len(items))
+	for i, item := range items {
+		results[i] = models.BatchResult{Index: item.Index, Operation: &models.WalletOperation{WalletID: item.WalletID, Amount: item.Amount}}
+	}
+	return results, nil
+}
+
+func (m *MockWalletRepository) WithReconcileLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (m *MockWalletRepository) FindBalanceMismatches(ctx context.Context) (int, []models.BalanceMismatch, error) {
+	if m.FindMismatchesFunc != nil {
+		return m.FindMismatchesFunc(ctx)
+	}
+	return 0, nil, nil
+}
+
+func (m *MockWalletRepository) RepairBalance(ctx context.Context, runID, walletID uuid.UUID, currency models.Currency, actor string) (*models.BalanceAdjustment, error) {
+	if m.RepairBalanceFunc != nil {
+		return m.RepairBalanceFunc(ctx, runID, walletID, currency, actor)
+	}
+	return &models.BalanceAdjustment{ID: uuid.New(), RunID: runID, WalletID: walletID, Currency: currency, Actor: actor}, nil
+}
+
+func (m *MockWalletRepository) SaveReconciliationRun(ctx context.Context, report *models.ReconciliationReport) error {
+	m.runs = append(m.runs, report)
+	return nil
+}
+
+func (m *MockWalletRepository) GetBalanceAt(ctx context.Context, walletID uuid.UUID, currency models.Currency, at time.Time) (int64, error) {
+	if m.GetBalanceAtFunc != nil {
+		return m.GetBalanceAtFunc(ctx, walletID, currency, at)
+	}
+	return 0, nil
+}
+
+func (m *MockWalletRepository) SnapshotBalances(ctx context.Context, every int) (int, error) {
+	return 0, nil
+}
+
+func (m *MockWalletRepository) GetOperation(ctx context.Context, operationID uuid.UUID) (*models.WalletOperation, error) {
+	return &models.WalletOperation{ID: operationID, Operation: models.OperationTypeDeposit}, nil
+}
+
+func (m *MockWalletRepository) ReverseOperation(ctx context.Context, operationID uuid.UUID, amount *int64, allowNegative bool) (*models.WalletOperation, error) {
+	if m.ReverseFunc != nil {
+		return m.ReverseFunc(ctx, operationID, amount, allowNegative)
+	}
+	return &models.WalletOperation{ID: uuid.New(), Operation: models.OperationTypeReversal, ReversalOf: &operationID}, nil
+}
+
+func (m *MockWalletRepository) ListStatusChanges(ctx context.Context, walletID uuid.UUID) ([]models.WalletStatusChange, error) {
+	return []models.WalletStatusChange{}, nil
+}
+
 func TestWalletService_GetBalance(t *testing.T) {
 	tests := []struct {
 		name      string
 		walletID  uuid.UUID
 		mockSetup func(*MockWalletRepository)
-		want      int
+		want      int64
 		wantErr   bool
 	}{
 		{
 			name:     "successful get balance",
 			walletID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"),
 			mockSetup: func(m *MockWalletRepository) {
-				m.GetBalanceFunc = func(ctx context.Context, walletID uuid.UUID) (int, error) {
+				m.GetBalanceFunc = func(ctx context.Context, walletID uuid.UUID, currency models.Currency) (int64, error) {
 					return 1000, nil
 				}
 			},
@@ -60,8 +252,8 @@ func TestWalletService_GetBalance(t *testing.T) {
 			name:     "wallet not found",
 			walletID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"),
 			mockSetup: func(m *MockWalletRepository) {
-				m.GetBalanceFunc = func(ctx context.Context, walletID uuid.UUID) (int, error) {
-					return 0, errors.New("balance not found")
+				m.GetBalanceFunc = func(ctx context.Context, walletID uuid.UUID, currency models.Currency) (int64, error) {
+					return 0, models.ErrWalletNotFound
 				}
 			},
 			want:    0,
@@ -74,8 +266,8 @@ func TestWalletService_GetBalance(t *testing.T) {
 			mockRepo := &MockWalletRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := &WalletService{walletRepo: mockRepo}
-			balance, err := service.GetBalance(context.Background(), tt.walletID)
+			service := NewWalletService(mockRepo, logging.Discard())
+			balance, err := service.GetBalance(context.Background(), tt.walletID, models.DefaultCurrency)
 
 			if tt.wantErr {
 				if err == nil {
@@ -93,23 +285,55 @@ func TestWalletService_GetBalance(t *testing.T) {
 	}
 }
 
+func TestWalletService_OpenWallet(t *testing.T) {
+	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	t.Run("new wallet", func(t *testing.T) {
+		mockRepo := &MockWalletRepository{}
+		service := NewWalletService(mockRepo, logging.Discard())
+		wallet, err := service.OpenWallet(context.Background(), &models.Principal{ID: "merchant-1", Role: models.RoleClient}, walletID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if wallet.ID != walletID {
+			t.Errorf("got wallet %s, want %s", wallet.ID, walletID)
+		}
+		if len(mockRepo.owners) != 1 || mockRepo.owners[0] != "merchant-1" {
+			t.Errorf("creator should become the owner, got owners %v", mockRepo.owners)
+		}
+	})
+
+	t.Run("existing wallet", func(t *testing.T) {
+		mockRepo := &MockWalletRepository{
+			CreateWalletFunc: func(ctx context.Context, walletID uuid.UUID) (bool, error) {
+				return false, nil
+			},
+		}
+		service := NewWalletService(mockRepo, logging.Discard())
+		if _, err := service.OpenWallet(context.Background(), &models.Principal{ID: "ops", Role: models.RoleAdmin}, walletID); !errors.Is(err, models.ErrWalletExists) {
+			t.Errorf("expected ErrWalletExists, got %v", err)
+		}
+	})
+}
+
 func TestWalletService_UpdateBalance(t *testing.T) {
 	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
 
 	tests := []struct {
 		name          string
 		operationType models.OperationType
-		amount        int
+		currency      models.Currency
+		amount        int64
 		mockSetup     func(*MockWalletRepository)
 		wantErr       bool
-		errContains   string
+		wantErrIs     error
 	}{
 		{
 			name:          "successful deposit",
 			operationType: models.OperationTypeDeposit,
 			amount:        1000,
 			mockSetup: func(m *MockWalletRepository) {
-				m.UpdateBalanceFunc = func(ctx context.Context, walletID uuid.UUID, operationType models.OperationType, amount int) (bool, error) {
+				m.UpdateBalanceFunc = func(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error) {
 					return true, nil
 				}
 			},
@@ -120,7 +344,7 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			operationType: models.OperationTypeWithdraw,
 			amount:        500,
 			mockSetup: func(m *MockWalletRepository) {
-				m.UpdateBalanceFunc = func(ctx context.Context, walletID uuid.UUID, operationType models.OperationType, amount int) (bool, error) {
+				m.UpdateBalanceFunc = func(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error) {
 					return true, nil
 				}
 			},
@@ -131,12 +355,12 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			operationType: models.OperationTypeWithdraw,
 			amount:        2000,
 			mockSetup: func(m *MockWalletRepository) {
-				m.UpdateBalanceFunc = func(ctx context.Context, walletID uuid.UUID, operationType models.OperationType, amount int) (bool, error) {
-					return false, errors.New("insufficient funds")
+				m.UpdateBalanceFunc = func(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error) {
+					return false, models.ErrInsufficientFunds
 				}
 			},
-			wantErr:     true,
-			errContains: "insufficient funds",
+			wantErr:   true,
+			wantErrIs: models.ErrInsufficientFunds,
 		},
 		{
 			name:          "invalid operation type",
@@ -144,7 +368,16 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			amount:        1000,
 			mockSetup:     func(m *MockWalletRepository) {},
 			wantErr:       true,
-			errContains:   "invalid operationType",
+			wantErrIs:     models.ErrInvalidOperation,
+		},
+		{
+			name:          "unknown currency",
+			operationType: models.OperationTypeDeposit,
+			currency:      models.Currency("ABC"),
+			amount:        1000,
+			mockSetup:     func(m *MockWalletRepository) {},
+			wantErr:       true,
+			wantErrIs:     models.ErrInvalidCurrency,
 		},
 		{
 			name:          "zero amount",
@@ -152,7 +385,7 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			amount:        0,
 			mockSetup:     func(m *MockWalletRepository) {},
 			wantErr:       true,
-			errContains:   "amount cannot be zero",
+			wantErrIs:     models.ErrInvalidAmount,
 		},
 	}
 
@@ -161,17 +394,20 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			mockRepo := &MockWalletRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := &WalletService{walletRepo: mockRepo}
-			_, err := service.UpdateBalance(context.Background(), walletID, tt.operationType, tt.amount)
+			service := NewWalletService(mockRepo, logging.Discard())
+			currency := tt.currency
+			if currency == "" {
+				currency = models.DefaultCurrency
+			}
+
+			_, err := service.UpdateBalance(context.Background(), walletID, currency, tt.operationType, tt.amount)
 
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("expected error but got none")
 				}
-				if tt.errContains != "" && err != nil {
-					if !strings.Contains(err.Error(), tt.errContains) {
-						t.Errorf("error message should contain '%s', got '%s'", tt.errContains, err.Error())
-					}
+				if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("error should wrap '%v', got '%v'", tt.wantErrIs, err)
 				}
 			} else {
 				if err != nil {
@@ -181,3 +417,751 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 		})
 	}
 }
+
+func TestWalletService_ListOperations(t *testing.T) {
+	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	ledger := make([]models.WalletOperation, 5)
+	for i := range ledger {
+		ledger[i] = models.WalletOperation{ID: uuid.New(), Seq: int64(len(ledger) - i), WalletID: walletID, Operation: models.OperationTypeDeposit, Amount: 10}
+	}
+
+	mockRepo := &MockWalletRepository{
+		ListOperationsFunc: func(ctx context.Context, walletID uuid.UUID, filter models.OperationFilter) ([]models.WalletOperation, error) {
+			var page []models.WalletOperation
+			for _, op := range ledger {
+				if filter.BeforeSeq > 0 && op.Seq >= filter.BeforeSeq {
+					continue
+				}
+				if len(page) == filter.Limit {
+					break
+				}
+				page = append(page, op)
+			}
+			return page, nil
+		},
+	}
+	service := NewWalletService(mockRepo, logging.Discard())
+
+	var seen []int64
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		ops, next, err := service.ListOperations(context.Background(), walletID, models.OperationFilter{Limit: 2}, cursor)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, op := range ops {
+			seen = append(seen, op.Seq)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	want := []int64{5, 4, 3, 2, 1}
+	if len(seen) != len(want) {
+		t.Fatalf("got seqs %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("got seqs %v, want %v", seen, want)
+		}
+	}
+
+	if _, _, err := service.ListOperations(context.Background(), walletID, models.OperationFilter{}, "not-a-cursor"); !errors.Is(err, models.ErrInvalidCursor) {
+		t.Errorf("expected invalid cursor error, got %v", err)
+	}
+}
+
+func TestWalletService_Transfer(t *testing.T) {
+	from := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	to := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+
+	tests := []struct {
+		name      string
+		from      uuid.UUID
+		to        uuid.UUID
+		amount    int64
+		mockSetup func(*MockWalletRepository)
+		wantErr   bool
+		wantErrIs error
+	}{
+		{
+			name:      "successful transfer",
+			from:      from,
+			to:        to,
+			amount:    100,
+			mockSetup: func(m *MockWalletRepository) {},
+		},
+		{
+			name:      "same wallet",
+			from:      from,
+			to:        from,
+			amount:    100,
+			mockSetup: func(m *MockWalletRepository) {},
+			wantErr:   true,
+			wantErrIs: models.ErrSameWallet,
+		},
+		{
+			name:      "zero amount",
+			from:      from,
+			to:        to,
+			amount:    0,
+			mockSetup: func(m *MockWalletRepository) {},
+			wantErr:   true,
+			wantErrIs: models.ErrInvalidAmount,
+		},
+		{
+			name:   "insufficient funds",
+			from:   from,
+			to:     to,
+			amount: 100,
+			mockSetup: func(m *MockWalletRepository) {
+				m.TransferFunc = func(ctx context.Context, fromWalletID, toWalletID uuid.UUID, currency models.Currency, amount int64) (*models.Transfer, error) {
+					return nil, models.ErrInsufficientFunds
+				}
+			},
+			wantErr:   true,
+			wantErrIs: models.ErrInsufficientFunds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockWalletRepository{}
+			tt.mockSetup(mockRepo)
+
+			service := NewWalletService(mockRepo, logging.Discard())
+			transfer, err := service.Transfer(context.Background(), tt.from, tt.to, models.DefaultCurrency, tt.amount)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error but got none")
+				}
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("error should wrap '%v', got '%v'", tt.wantErrIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if transfer.Amount != tt.amount {
+				t.Errorf("got amount %d, want %d", transfer.Amount, tt.amount)
+			}
+		})
+	}
+}
+
+func TestWalletService_CreateHold(t *testing.T) {
+	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	tests := []struct {
+		name      string
+		amount    int64
+		ttl       time.Duration
+		wantTTL   time.Duration
+		wantErrIs error
+	}{
+		{name: "default ttl", amount: 100, ttl: 0, wantTTL: DefaultHoldTTL},
+		{name: "custom ttl", amount: 100, ttl: time.Hour, wantTTL: time.Hour},
+		{name: "ttl too long", amount: 100, ttl: MaxHoldTTL + time.Second, wantErrIs: models.ErrInvalidHoldTTL},
+		{name: "zero amount", amount: 0, ttl: time.Hour, wantErrIs: models.ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTTL time.Duration
+			mockRepo := &MockWalletRepository{
+				CreateHoldFunc: func(ctx context.Context, walletID uuid.UUID, currency models.Currency, amount int64, ttl time.Duration) (*models.Hold, error) {
+					gotTTL = ttl
+					return &models.Hold{ID: uuid.New(), Amount: amount}, nil
+				},
+			}
+
+			service := NewWalletService(mockRepo, logging.Discard())
+			_, err := service.CreateHold(context.Background(), walletID, models.DefaultCurrency, tt.amount, tt.ttl)
+
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("error should wrap '%v', got '%v'", tt.wantErrIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotTTL != tt.wantTTL {
+				t.Errorf("got ttl %v, want %v", gotTTL, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestWalletService_CaptureHold(t *testing.T) {
+	holdID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440010")
+
+	var captured int64
+	mockRepo := &MockWalletRepository{
+		GetHoldFunc: func(ctx context.Context, holdID uuid.UUID) (*models.Hold, error) {
+			return &models.Hold{ID: holdID, Amount: 700, Status: models.HoldStatusActive}, nil
+		},
+		CaptureHoldFunc: func(ctx context.Context, holdID uuid.UUID, amount int64) (*models.Hold, error) {
+			captured = amount
+			return &models.Hold{ID: holdID, Amount: 700, CapturedAmount: amount, Status: models.HoldStatusCaptured}, nil
+		},
+	}
+	service := NewWalletService(mockRepo, logging.Discard())
+
+	if _, err := service.CaptureHold(context.Background(), holdID, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if captured != 700 {
+		t.Errorf("full capture should capture the held amount, got %d", captured)
+	}
+
+	partial := int64(250)
+	if _, err := service.CaptureHold(context.Background(), holdID, &partial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if captured != partial {
+		t.Errorf("partial capture: got %d, want %d", captured, partial)
+	}
+
+	zero := int64(0)
+	if _, err := service.CaptureHold(context.Background(), holdID, &zero); !errors.Is(err, models.ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
+func TestWalletService_GetLimits(t *testing.T) {
+	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	daily := int64(5000)
+	walletDaily := int64(100)
+
+	policies := map[bool]*models.WithdrawalLimits{
+		false: {Currency: models.DefaultCurrency, Daily: &daily},
+	}
+	mockRepo := &MockWalletRepository{
+		GetLimitsFunc: func(ctx context.Context, walletID *uuid.UUID, currency models.Currency) (*models.WithdrawalLimits, error) {
+			if limits, ok := policies[walletID != nil]; ok {
+				return limits, nil
+			}
+			return nil, models.ErrLimitsNotFound
+		},
+	}
+	service := NewWalletService(mockRepo, logging.Discard())
+
+	limits, err := service.GetLimits(context.Background(), &walletID, models.DefaultCurrency)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if limits.WalletID != nil || *limits.Daily != daily {
+		t.Errorf("wallet without own policy should get the default one, got %+v", limits)
+	}
+
+	policies[true] = &models.WithdrawalLimits{WalletID: &walletID, Currency: models.DefaultCurrency, Daily: &walletDaily}
+	limits, err = service.GetLimits(context.Background(), &walletID, models.DefaultCurrency)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *limits.Daily != walletDaily {
+		t.Errorf("wallet policy should override the default one, got daily %d", *limits.Daily)
+	}
+
+	negative := int64(-1)
+	_, err = service.SetLimits(context.Background(), models.WithdrawalLimits{Currency: models.DefaultCurrency, Monthly: &negative})
+	if !errors.Is(err, models.ErrInvalidLimits) {
+		t.Errorf("expected ErrInvalidLimits, got %v", err)
+	}
+}
+
+func TestWalletService_SetWalletStatus(t *testing.T) {
+	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	tests := []struct {
+		name      string
+		status    models.WalletStatus
+		reason    string
+		actor     string
+		mockSetup func(*MockWalletRepository)
+		wantErrIs error
+	}{
+		{
+			name:   "freeze",
+			status: models.WalletStatusFrozen,
+			reason: "chargeback investigation",
+			actor:  "support@itk",
+		},
+		{
+			name:      "unknown status",
+			status:    models.WalletStatus("LOCKED"),
+			reason:    "test",
+			actor:     "support@itk",
+			wantErrIs: models.ErrInvalidStatus,
+		},
+		{
+			name:      "missing reason",
+			status:    models.WalletStatusFrozen,
+			reason:    "  ",
+			actor:     "support@itk",
+			wantErrIs: models.ErrInvalidStatus,
+		},
+		{
+			name:   "close non-empty wallet",
+			status: models.WalletStatusClosed,
+			reason: "customer request",
+			actor:  "support@itk",
+			mockSetup: func(m *MockWalletRepository) {
+				m.SetStatusFunc = func(ctx context.Context, walletID uuid.UUID, status models.WalletStatus, reason, actor string) (*models.WalletStatusChange, error) {
+					return nil, models.ErrWalletNotEmpty
+				}
+			},
+			wantErrIs: models.ErrWalletNotEmpty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockWalletRepository{}
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockRepo)
+			}
+			service := NewWalletService(mockRepo, logging.Discard())
+
+			change, err := service.SetWalletStatus(context.Background(), walletID, tt.status, tt.reason, tt.actor)
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("SetWalletStatus() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if change.ToStatus != tt.status {
+				t.Errorf("SetWalletStatus() status = %v, want %v", change.ToStatus, tt.status)
+			}
+		})
+	}
+}
+
+func TestWalletService_OperationLog(t *testing.T) {
+	var logs bytes.Buffer
+	mockRepo := &MockWalletRepository{
+		UpdateBalanceFunc: func(ctx context.Context, walletID uuid.UUID, currency models.Currency, operationType models.OperationType, amount int64) (bool, error) {
+			return false, models.ErrInsufficientFunds
+		},
+	}
+	service := NewWalletService(mockRepo, logging.New(&logs, slog.LevelInfo))
+
+	walletID := uuid.New()
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+	_, _ = service.UpdateBalance(ctx, walletID, models.DefaultCurrency, models.OperationTypeWithdraw, 300)
+
+	lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+	if len(lines) != 1 {
+		t.Fatalf("expected one log line per operation, got %d: %s", len(lines), logs.String())
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(lines[0], &line); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	want := map[string]any{
+		"wallet_id":  walletID.String(),
+		"operation":  "WITHDRAW",
+		"amount":     float64(300),
+		"outcome":    "insufficient_funds",
+		"request_id": "req-42",
+	}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("log field %s = %v, want %v", key, line[key], value)
+		}
+	}
+	if _, ok := line["duration"]; !ok {
+		t.Error("log line has no duration")
+	}
+}
+
+func TestWalletService_OperationLogRejected(t *testing.T) {
+	tests := []struct {
+		name          string
+		operationType models.OperationType
+		currency      models.Currency
+		amount        int64
+		wantOperation string
+	}{
+		{name: "invalid operation", operationType: "STEAL", currency: models.DefaultCurrency, amount: 100, wantOperation: "INVALID"},
+		{name: "invalid currency", operationType: models.OperationTypeDeposit, currency: "XXX", amount: 100, wantOperation: "DEPOSIT"},
+		{name: "invalid amount", operationType: models.OperationTypeWithdraw, currency: models.DefaultCurrency, amount: 0, wantOperation: "WITHDRAW"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			service := NewWalletService(&MockWalletRepository{}, logging.New(&logs, slog.LevelInfo))
+
+			if _, err := service.UpdateBalance(context.Background(), uuid.New(), tt.currency, tt.operationType, tt.amount); err == nil {
+				t.Fatal("expected a validation error")
+			}
+
+			var line map[string]any
+			if err := json.Unmarshal(bytes.TrimSpace(logs.Bytes()), &line); err != nil {
+				t.Fatalf("expected one JSON log line, got %q: %v", logs.String(), err)
+			}
+			if line["operation"] != tt.wantOperation || line["outcome"] != "rejected" {
+				t.Errorf("logged operation %v with outcome %v, want %s rejected", line["operation"], line["outcome"], tt.wantOperation)
+			}
+		})
+	}
+}
+
+func TestWalletService_AuthorizeWallet(t *testing.T) {
+	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	tests := []struct {
+		name      string
+		principal *models.Principal
+		owner     bool
+		missing   bool
+		wantErrIs error
+	}{
+		{name: "anonymous", principal: nil, wantErrIs: models.ErrUnauthenticated},
+		{name: "admin", principal: &models.Principal{ID: "ops", Role: models.RoleAdmin}},
+		{name: "owner", principal: &models.Principal{ID: "merchant-1", Role: models.RoleClient}, owner: true},
+		{name: "token grant", principal: &models.Principal{ID: "merchant-2", Role: models.RoleClient, WalletIDs: []uuid.UUID{walletID}}},
+		{name: "stranger", principal: &models.Principal{ID: "merchant-3", Role: models.RoleClient}, wantErrIs: models.ErrForbidden},
+		{name: "unknown wallet", principal: &models.Principal{ID: "merchant-3", Role: models.RoleClient}, missing: true, wantErrIs: models.ErrWalletNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockWalletRepository{
+				IsWalletOwnerFunc: func(ctx context.Context, walletID uuid.UUID, principal string) (bool, error) {
+					return tt.owner, nil
+				},
+				GetWalletFunc: func(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+					if tt.missing {
+						return nil, models.ErrWalletNotFound
+					}
+					return &models.Wallet{ID: id}, nil
+				},
+			}
+			service := NewWalletService(mockRepo, logging.Discard())
+
+			err := service.AuthorizeWallet(context.Background(), tt.principal, walletID)
+			if tt.wantErrIs == nil && err != nil {
+				t.Errorf("AuthorizeWallet() unexpected error: %v", err)
+			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("AuthorizeWallet() error = %v, want %v", err, tt.wantErrIs)
+			}
+		})
+	}
+}
+
+func TestWalletService_ProcessBatch(t *testing.T) {
+	owned := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	foreign := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+	unknown := uuid.MustParse("550e8400-e29b-41d4-a716-446655440002")
+	principal := &models.Principal{ID: "merchant-1", Role: models.RoleClient}
+
+	items := []models.BatchItem{
+		{WalletID: owned, Operation: models.OperationTypeDeposit, Currency: models.DefaultCurrency, Amount: 100},
+		{WalletID: foreign, Operation: models.OperationTypeDeposit, Currency: models.DefaultCurrency, Amount: 100},
+		{WalletID: owned, Operation: models.OperationTypeWithdraw, Currency: models.DefaultCurrency, Amount: 0},
+		{WalletID: owned, Operation: models.OperationTypeWithdraw, Currency: models.DefaultCurrency, Amount: 50},
+	}
+
+	tests := []struct {
+		name       string
+		mode       models.BatchMode
+		items      []models.BatchItem
+		autoCreate bool
+		wantErrIs  error
+		wantIndex  int
+		wantFails  []int
+		wantNew    []uuid.UUID
+	}{
+		{name: "invalid mode", mode: "PARTIAL", items: items, wantErrIs: models.ErrInvalidBatchMode},
+		{name: "empty batch", mode: models.BatchModeAtomic, wantErrIs: models.ErrInvalidBatchSize},
+		{name: "atomic fails on first rejected item", mode: models.BatchModeAtomic, items: items, wantErrIs: models.ErrInvalidAmount, wantIndex: 2},
+		{name: "atomic forbidden wallet", mode: models.BatchModeAtomic, items: items[:2], wantErrIs: models.ErrForbidden, wantIndex: 1},
+		{name: "best effort reports each item", mode: models.BatchModeBestEffort, items: items, wantFails: []int{1, 2}},
+		{
+			name:       "unknown wallet is created by the batch",
+			mode:       models.BatchModeAtomic,
+			items:      []models.BatchItem{{WalletID: unknown, Operation: models.OperationTypeDeposit, Currency: models.DefaultCurrency, Amount: 100}},
+			autoCreate: true,
+			wantNew:    []uuid.UUID{unknown},
+		},
+		{
+			name:      "unknown wallet without auto-create",
+			mode:      models.BatchModeBestEffort,
+			items:     []models.BatchItem{{WalletID: unknown, Operation: models.OperationTypeDeposit, Currency: models.DefaultCurrency, Amount: 100}},
+			wantFails: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var applied []models.BatchItem
+			var created []uuid.UUID
+			mockRepo := &MockWalletRepository{
+				IsWalletOwnerFunc: func(ctx context.Context, walletID uuid.UUID, principal string) (bool, error) {
+					return walletID == owned, nil
+				},
+				GetWalletFunc: func(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+					if walletID == unknown {
+						return nil, models.ErrWalletNotFound
+					}
+					return &models.Wallet{ID: walletID}, nil
+				},
+				CreateWalletFunc: func(ctx context.Context, walletID uuid.UUID) (bool, error) {
+					t.Error("batch created a wallet outside its transaction")
+					return true, nil
+				},
+				ApplyBatchFunc: func(ctx context.Context, mode models.BatchMode, items []models.BatchItem, newWallets []uuid.UUID, owner string) ([]models.BatchResult, error) {
+					applied, created = items, newWallets
+					if len(newWallets) > 0 && owner != principal.ID {
+						t.Errorf("ApplyBatch() owner = %q, want %q", owner, principal.ID)
+					}
+					results := make([]models.BatchResult, len(items))
+					for i, item := range items {
+						results[i] = models.BatchResult{Index: item.Index, Operation: &models.WalletOperation{WalletID: item.WalletID}}
+					}
+					return results, nil
+				},
+			}
+			service := NewWalletService(mockRepo, logging.Discard())
+
+			results, err := service.ProcessBatch(context.Background(), principal, tt.mode, tt.items, tt.autoCreate)
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("ProcessBatch() error = %v, want %v", err, tt.wantErrIs)
+				}
+				var itemErr *models.BatchItemError
+				if tt.wantIndex > 0 && (!errors.As(err, &itemErr) || itemErr.Index != tt.wantIndex) {
+					t.Errorf("ProcessBatch() error = %v, want failing index %d", err, tt.wantIndex)
+				}
+				if applied != nil {
+					t.Error("rejected batch reached the repository")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessBatch() unexpected error: %v", err)
+			}
+
+			if len(results) != len(tt.items) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.items))
+			}
+			var fails []int
+			for i, result := range results {
+				if result.Index != i {
+					t.Errorf("results[%d].Index = %d", i, result.Index)
+				}
+				if result.Err != nil {
+					fails = append(fails, i)
+				}
+			}
+			if !slices.Equal(fails, tt.wantFails) {
+				t.Errorf("failed items = %v, want %v", fails, tt.wantFails)
+			}
+			if len(applied) != len(tt.items)-len(tt.wantFails) {
+				t.Errorf("repository got %d items, want %d", len(applied), len(tt.items)-len(tt.wantFails))
+			}
+			if !slices.Equal(created, tt.wantNew) {
+				t.Errorf("new wallets = %v, want %v", created, tt.wantNew)
+			}
+		})
+	}
+}
+
+func TestWalletService_Reconcile(t *testing.T) {
+	walletA := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	walletB := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+	mismatches := []models.BalanceMismatch{
+		{WalletID: walletA, Currency: models.DefaultCurrency, StoredBalance: 500, LedgerBalance: 100, Difference: 400},
+		{WalletID: walletB, Currency: models.DefaultCurrency, StoredBalance: 0, LedgerBalance: -50, Difference: 50},
+	}
+
+	tests := []struct {
+		name         string
+		repair       bool
+		actor        string
+		wantErrIs    error
+		wantRepaired int
+		wantRepairs  int
+	}{
+		{name: "report only", wantRepaired: 0},
+		{name: "repair", repair: true, actor: "finance", wantRepaired: 1, wantRepairs: 2},
+		{name: "repair without actor", repair: true, actor: " ", wantErrIs: models.ErrInvalidPrincipal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repairs := 0
+			mockRepo := &MockWalletRepository{
+				FindMismatchesFunc: func(ctx context.Context) (int, []models.BalanceMismatch, error) {
+					return 10, slices.Clone(mismatches), nil
+				},
+				RepairBalanceFunc: func(ctx context.Context, runID, walletID uuid.UUID, currency models.Currency, actor string) (*models.BalanceAdjustment, error) {
+					repairs++
+					if actor != tt.actor {
+						t.Errorf("RepairBalance() actor = %q, want %q", actor, tt.actor)
+					}
+					if walletID == walletB {
+						return nil, errors.New("balance would become negative")
+					}
+					return &models.BalanceAdjustment{RunID: runID, WalletID: walletID}, nil
+				},
+			}
+			service := NewWalletService(mockRepo, logging.Discard())
+
+			report, err := service.Reconcile(context.Background(), tt.repair, tt.actor)
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("Reconcile() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reconcile() unexpected error: %v", err)
+			}
+
+			if report.BalancesChecked != 10 || len(report.Mismatches) != len(mismatches) {
+				t.Errorf("report checked %d with %d mismatches, want 10 with %d", report.BalancesChecked, len(report.Mismatches), len(mismatches))
+			}
+			if report.Repaired != tt.wantRepaired || repairs != tt.wantRepairs {
+				t.Errorf("repaired %d of %d attempts, want %d of %d", report.Repaired, repairs, tt.wantRepaired, tt.wantRepairs)
+			}
+			if tt.repair && (!report.Mismatches[0].Repaired || report.Mismatches[1].RepairError == "") {
+				t.Errorf("mismatch outcomes = %+v", report.Mismatches)
+			}
+			if len(mockRepo.runs) != 1 || mockRepo.runs[0].ID != report.ID {
+				t.Error("reconciliation run was not saved")
+			}
+		})
+	}
+}
+
+func TestWalletService_GetBalanceAt(t *testing.T) {
+	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	past := time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		currency    models.Currency
+		at          *time.Time
+		wantBalance int64
+		wantErrIs   error
+	}{
+		{name: "current balance", currency: models.DefaultCurrency, wantBalance: 500},
+		{name: "past balance from ledger", currency: models.DefaultCurrency, at: &past, wantBalance: 200},
+		{name: "future time", currency: models.DefaultCurrency, at: &future, wantErrIs: models.ErrFutureBalanceTime},
+		{name: "invalid currency", currency: "XXX", at: &past, wantErrIs: models.ErrInvalidCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockWalletRepository{
+				GetBalanceFunc: func(ctx context.Context, walletID uuid.UUID, currency models.Currency) (int64, error) {
+					return 500, nil
+				},
+				GetBalanceAtFunc: func(ctx context.Context, walletID uuid.UUID, currency models.Currency, at time.Time) (int64, error) {
+					if !at.Equal(past) {
+						t.Errorf("GetBalanceAt() at = %s, want %s", at, past)
+					}
+					return 200, nil
+				},
+			}
+			service := NewWalletService(mockRepo, logging.Discard())
+
+			balance, err := service.GetBalanceAt(context.Background(), walletID, tt.currency, tt.at)
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("GetBalanceAt() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetBalanceAt() unexpected error: %v", err)
+			}
+
+			if balance.Balance != tt.wantBalance || balance.WalletID != walletID || balance.Currency != tt.currency {
+				t.Errorf("GetBalanceAt() = %+v, want balance %d", balance, tt.wantBalance)
+			}
+			if tt.at != nil && !balance.At.Equal(*tt.at) {
+				t.Errorf("GetBalanceAt() at = %s, want %s", balance.At, *tt.at)
+			}
+		})
+	}
+}
+
+func TestWalletService_ReverseOperation(t *testing.T) {
+	operationID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	client := &models.Principal{ID: "merchant-1", Role: models.RoleClient}
+	admin := &models.Principal{ID: "ops", Role: models.RoleAdmin}
+	amount := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name          string
+		principal     *models.Principal
+		amount        *int64
+		allowNegative bool
+		repoErr       error
+		wantErrIs     error
+		wantRepoCall  bool
+	}{
+		{name: "full reversal", principal: admin, wantRepoCall: true},
+		{name: "partial reversal", principal: admin, amount: amount(40), wantRepoCall: true},
+		{name: "zero amount", principal: admin, amount: amount(0), wantErrIs: models.ErrInvalidAmount},
+		{name: "client may not reverse", principal: client, wantErrIs: models.ErrForbidden},
+		{name: "client may not go negative", principal: client, allowNegative: true, wantErrIs: models.ErrForbidden},
+		{name: "anonymous may not reverse", wantErrIs: models.ErrForbidden},
+		{name: "admin may go negative", principal: admin, allowNegative: true, wantRepoCall: true},
+		{name: "already reversed", principal: admin, repoErr: models.ErrAlreadyReversed, wantErrIs: models.ErrAlreadyReversed, wantRepoCall: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			mockRepo := &MockWalletRepository{
+				ReverseFunc: func(ctx context.Context, id uuid.UUID, amount *int64, allowNegative bool) (*models.WalletOperation, error) {
+					called = true
+					if id != operationID || amount != tt.amount || allowNegative != tt.allowNegative {
+						t.Errorf("ReverseOperation() got (%s, %v, %v)", id, amount, allowNegative)
+					}
+					if tt.repoErr != nil {
+						return nil, tt.repoErr
+					}
+					return &models.WalletOperation{ID: uuid.New(), Operation: models.OperationTypeReversal, ReversalOf: &id}, nil
+				},
+			}
+			service := NewWalletService(mockRepo, logging.Discard())
+
+			reversal, err := service.ReverseOperation(context.Background(), tt.principal, operationID, tt.amount, tt.allowNegative)
+			if called != tt.wantRepoCall {
+				t.Errorf("repository called = %v, want %v", called, tt.wantRepoCall)
+			}
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("ReverseOperation() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReverseOperation() unexpected error: %v", err)
+			}
+			if reversal.ReversalOf == nil || *reversal.ReversalOf != operationID {
+				t.Errorf("ReverseOperation() reversalOf = %v, want %s", reversal.ReversalOf, operationID)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/repository"
+	"github.com/itk/wallet/internal/webhook"
+)
+
+const (
+	DefaultDeliveriesLimit = 50
+	MaxDeliveriesLimit     = 200
+)
+
+type WebhookService struct {
+	webhookRepo         repository.WebhookInterface
+	allowPrivateTargets bool
+}
+
+// NewWebhookService rejects subscriptions to loopback and private addresses
+// unless allowPrivateTargets is set, which is only meant for local development.
+func NewWebhookService(webhookRepo repository.WebhookInterface, allowPrivateTargets bool) *WebhookService {
+	return &WebhookService{
+		webhookRepo:         webhookRepo,
+		allowPrivateTargets: allowPrivateTargets,
+	}
+}
+
+// CreateSubscription registers a URL for the principal's wallet events. An
+// admin's subscription receives the events of every wallet. The signing
+// secret is only returned here and by RotateSecret.
+func (s *WebhookService) CreateSubscription(ctx context.Context, principal *models.Principal, rawURL string, eventTypes []string) (*models.WebhookSubscription, error) {
+	if principal == nil {
+		return nil, models.ErrUnauthenticated
+	}
+	if err := s.validateSubscription(ctx, rawURL, eventTypes); err != nil {
+		return nil, err
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	sub, err := s.webhookRepo.CreateSubscription(ctx, models.WebhookSubscription{
+		Principal:  principal.ID,
+		AllWallets: principal.IsAdmin(),
+		URL:        rawURL,
+		EventTypes: normalizeEventTypes(eventTypes),
+		Secret:     secret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	sub.Secret = secret
+
+	return sub, nil
+}
+
+// ListSubscriptions returns the principal's subscriptions, or every
+// subscription for an admin.
+func (s *WebhookService) ListSubscriptions(ctx context.Context, principal *models.Principal) ([]models.WebhookSubscription, error) {
+	if principal == nil {
+		return nil, models.ErrUnauthenticated
+	}
+
+	owner := principal.ID
+	if principal.IsAdmin() {
+		owner = ""
+	}
+
+	subs, err := s.webhookRepo.ListSubscriptions(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// GetSubscription returns the subscription if the principal created it or
+// is an admin.
+func (s *WebhookService) GetSubscription(ctx context.Context, principal *models.Principal, id uuid.UUID) (*models.WebhookSubscription, error) {
+	if principal == nil {
+		return nil, models.ErrUnauthenticated
+	}
+
+	sub, err := s.webhookRepo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	if !principal.IsAdmin() && sub.Principal != principal.ID {
+		return nil, models.ErrForbidden
+	}
+
+	return sub, nil
+}
+
+func (s *WebhookService) UpdateSubscription(ctx context.Context, principal *models.Principal, id uuid.UUID, rawURL string, eventTypes []string) (*models.WebhookSubscription, error) {
+	if _, err := s.GetSubscription(ctx, principal, id); err != nil {
+		return nil, err
+	}
+	if err := s.validateSubscription(ctx, rawURL, eventTypes); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.webhookRepo.UpdateSubscription(ctx, id, rawURL, normalizeEventTypes(eventTypes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+// RotateSecret replaces the signing secret. Deliveries sent after the call
+// are signed with the new secret only.
+func (s *WebhookService) RotateSecret(ctx context.Context, principal *models.Principal, id uuid.UUID) (*models.WebhookSubscription, error) {
+	if _, err := s.GetSubscription(ctx, principal, id); err != nil {
+		return nil, err
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	sub, err := s.webhookRepo.RotateSecret(ctx, id, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+
+	return sub, nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, principal *models.Principal, id uuid.UUID) error {
+	if _, err := s.GetSubscription(ctx, principal, id); err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.DeleteSubscription(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first.
+func (s *WebhookService) ListDeliveries(ctx context.Context, principal *models.Principal, id uuid.UUID, status models.DeliveryStatus, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(ctx, principal, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultDeliveriesLimit
+	}
+	if limit > MaxDeliveriesLimit {
+		limit = MaxDeliveriesLimit
+	}
+
+	deliveries, err := s.webhookRepo.ListDeliveries(ctx, id, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery queues a delivery again, typically a dead-lettered one.
+func (s *WebhookService) RetryDelivery(ctx context.Context, principal *models.Principal, id, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(ctx, principal, id); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.webhookRepo.RetryDelivery(ctx, id, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+func (s *WebhookService) validateSubscription(ctx context.Context, rawURL string, eventTypes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.ErrInvalidWebhookURL
+	}
+	if !s.allowPrivateTargets {
+		if err := webhook.CheckTarget(ctx, rawURL); err != nil {
+			return err
+		}
+	}
+
+	for _, eventType := range eventTypes {
+		if !models.IsValidEventType(eventType) {
+			return fmt.Errorf("%w: %s", models.ErrInvalidEventType, eventType)
+		}
+	}
+
+	return nil
+}
+
+func normalizeEventTypes(eventTypes []string) []string {
+	if eventTypes == nil {
+		return []string{}
+	}
+
+	return eventTypes
+}
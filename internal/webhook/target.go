@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/itk/wallet/internal/models"
+)
+
+// IsPublicAddr reports whether addr may receive webhooks. Loopback,
+// private, link-local, unspecified and multicast addresses are reachable
+// only from inside our network, so posting to them would let a merchant
+// probe internal services with our signed requests.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsUnspecified() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() && !addr.IsMulticast()
+}
+
+// CheckTarget fails unless every address the URL's host resolves to is
+// public. The dispatcher checks again when it dials, because the DNS
+// answer may change after registration.
+func CheckTarget(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return models.ErrInvalidWebhookURL
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !IsPublicAddr(addr) {
+			return fmt.Errorf("%w: %s", models.ErrWebhookURLNotPublic, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: cannot resolve %s", models.ErrInvalidWebhookURL, host)
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", models.ErrWebhookURLNotPublic, host, addr)
+		}
+	}
+
+	return nil
+}
+
+// denyPrivateAddrs is a net.Dialer Control hook that refuses connections to
+// addresses IsPublicAddr rejects. It runs after name resolution, so it also
+// covers redirects and DNS rebinding.
+func denyPrivateAddrs(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("webhook target %s: %w", address, err)
+	}
+	if !IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", models.ErrWebhookURLNotPublic, addrPort.Addr())
+	}
+
+	return nil
+}
+
+func newClient(cfg Config) *http.Client {
+	if cfg.AllowPrivateTargets {
+		return &http.Client{Timeout: cfg.Timeout}
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: denyPrivateAddrs}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the dialer check the proxy's address instead of the target's.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
@@ -0,0 +1,217 @@
+// Package webhook delivers wallet events to merchant endpoints with signed
+// requests, retries and dead-lettering.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/metrics"
+	"github.com/itk/wallet/internal/models"
+	"github.com/itk/wallet/internal/outbox"
+)
+
+const (
+	SignatureHeader  = "X-Wallet-Signature"
+	DeliveryIDHeader = "X-Delivery-ID"
+
+	secretPrefix = "whsec_"
+)
+
+type Config struct {
+	Enabled      bool
+	PollInterval time.Duration
+	BatchSize    int
+	Timeout      time.Duration
+	MaxAttempts  int
+	MaxBackoff   time.Duration
+	// AllowPrivateTargets turns off the checks that keep webhooks from
+	// reaching loopback and private addresses. Only for local development.
+	AllowPrivateTargets bool
+}
+
+// Sign returns the signature header value for a payload sent at ts:
+// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<payload>">.
+// Receivers recompute the HMAC with their secret and should reject stale
+// timestamps to prevent replays.
+func Sign(secret string, ts time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a new random signing secret.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+type Store interface {
+	EnqueueDeliveries(ctx context.Context, event models.Event) (int64, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingDelivery, error)
+	RecordAttempt(ctx context.Context, deliveryID uuid.UUID, attempt models.DeliveryAttempt) error
+}
+
+// Fanout is an outbox sink that queues each event for the subscriptions
+// interested in it. Sending happens later in the Dispatcher.
+type Fanout struct {
+	store Store
+}
+
+func NewFanout(store Store) *Fanout {
+	return &Fanout{store: store}
+}
+
+func (f *Fanout) Publish(ctx context.Context, event models.Event) error {
+	_, err := f.store.EnqueueDeliveries(ctx, event)
+	return err
+}
+
+func (f *Fanout) Close() error {
+	return nil
+}
+
+// Dispatcher sends queued deliveries. A delivery succeeds on any 2xx
+// response; otherwise it is retried with exponential backoff and marked
+// dead after MaxAttempts attempts.
+type Dispatcher struct {
+	store   Store
+	client  *http.Client
+	cfg     Config
+	backoff func(attempts int) time.Duration
+	now     func() time.Time
+	logger  *slog.Logger
+}
+
+func NewDispatcher(store Store, cfg Config, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		store:   store,
+		client:  newClient(cfg),
+		cfg:     cfg,
+		backoff: outbox.Backoff(cfg.PollInterval, cfg.MaxBackoff),
+		now:     time.Now,
+		logger:  logger,
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "failed to dispatch webhooks", slog.Any("error", err))
+		}
+		if err == nil && sent >= d.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of due deliveries and returns how many were
+// attempted. The batch is sent concurrently, so every delivery finishes
+// within one request timeout and well inside its lease; sending them one
+// by one would let the tail of the batch be claimed and sent again by
+// another replica.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	deliveries, err := d.store.ClaimDeliveries(ctx, d.cfg.BatchSize, 2*d.cfg.Timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(deliveries))
+	for i, delivery := range deliveries {
+		wg.Go(func() {
+			attempt := d.send(ctx, delivery)
+			errs[i] = d.store.RecordAttempt(context.WithoutCancel(ctx), delivery.ID, attempt)
+		})
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return 0, err
+	}
+
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery models.PendingDelivery) models.DeliveryAttempt {
+	attempt := models.DeliveryAttempt{}
+	statusCode, err := d.post(ctx, delivery)
+	attempt.StatusCode = statusCode
+
+	outcome := "delivered"
+	if err == nil {
+		attempt.Delivered = true
+	} else {
+		attempt.Error = err.Error()
+		attempts := delivery.Attempts + 1
+		if attempts >= d.cfg.MaxAttempts {
+			attempt.Dead = true
+			outcome = "dead"
+		} else {
+			attempt.RetryAfter = d.backoff(attempts)
+			outcome = "failed"
+		}
+		d.logger.WarnContext(ctx, "webhook delivery failed",
+			slog.String("delivery_id", delivery.ID.String()), slog.String("subscription_id", delivery.SubscriptionID.String()),
+			slog.Int("attempt", attempts), slog.Bool("dead", attempt.Dead), slog.Any("error", err))
+	}
+	metrics.WebhookDeliveries.WithLabelValues(outcome).Inc()
+
+	return attempt
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery models.PendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, d.now(), delivery.Payload))
+	req.Header.Set(DeliveryIDHeader, delivery.ID.String())
+	req.Header.Set(outbox.EventIDHeader, delivery.EventID.String())
+	req.Header.Set(outbox.EventTypeHeader, delivery.EventType)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
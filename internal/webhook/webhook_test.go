@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itk/wallet/internal/logging"
+	"github.com/itk/wallet/internal/models"
+)
+
+type fakeStore struct {
+	mu       sync.Mutex
+	pending  []models.PendingDelivery
+	attempts map[uuid.UUID]models.DeliveryAttempt
+}
+
+func (s *fakeStore) EnqueueDeliveries(ctx context.Context, event models.Event) (int64, error) {
+	return 0, nil
+}
+
+func (s *fakeStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+	claimed := s.pending
+	s.pending = nil
+	return claimed, nil
+}
+
+func (s *fakeStore) RecordAttempt(ctx context.Context, deliveryID uuid.UUID, attempt models.DeliveryAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[deliveryID] = attempt
+	return nil
+}
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	payload := []byte(`{"amount":100}`)
+
+	signature := Sign("whsec_test", ts, payload)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(payload)))
+	want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if signature != want {
+		t.Errorf("Sign() = %s, want %s", signature, want)
+	}
+	if Sign("other", ts, payload) == signature {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestDispatcher_RunOnce(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]*http.Request{}
+	bodies := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests[r.Header.Get(DeliveryIDHeader)] = r
+		bodies[r.Header.Get(DeliveryIDHeader)] = string(body)
+		mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ok := models.PendingDelivery{URL: server.URL + "/ok", Secret: "whsec_ok"}
+	ok.ID, ok.EventID, ok.EventType, ok.Payload = uuid.New(), uuid.New(), "wallet.deposit", json.RawMessage(`{"amount":100}`)
+	retry := models.PendingDelivery{URL: server.URL + "/fail", Secret: "whsec_fail"}
+	retry.ID, retry.Attempts, retry.Payload = uuid.New(), 1, json.RawMessage(`{}`)
+	dead := models.PendingDelivery{URL: server.URL + "/fail", Secret: "whsec_fail"}
+	dead.ID, dead.Attempts, dead.Payload = uuid.New(), 2, json.RawMessage(`{}`)
+
+	store := &fakeStore{pending: []models.PendingDelivery{ok, retry, dead}, attempts: map[uuid.UUID]models.DeliveryAttempt{}}
+	dispatcher := NewDispatcher(store, Config{PollInterval: time.Second, BatchSize: 10, Timeout: time.Second, MaxAttempts: 3, MaxBackoff: time.Minute, AllowPrivateTargets: true}, logging.Discard())
+	now := time.Unix(1700000000, 0)
+	dispatcher.now = func() time.Time { return now }
+
+	sent, err := dispatcher.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce() unexpected error: %v", err)
+	}
+	if sent != 3 {
+		t.Fatalf("RunOnce() sent %d, want 3", sent)
+	}
+
+	request := requests[ok.ID.String()]
+	if request == nil {
+		t.Fatal("ok delivery was not sent")
+	}
+	if got, want := request.Header.Get(SignatureHeader), Sign("whsec_ok", now, []byte(bodies[ok.ID.String()])); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+
+	if a := store.attempts[ok.ID]; !a.Delivered || a.StatusCode != 200 {
+		t.Errorf("ok attempt = %+v, want delivered", a)
+	}
+	if a := store.attempts[retry.ID]; a.Delivered || a.Dead || a.RetryAfter != 2*time.Second || a.StatusCode != 500 {
+		t.Errorf("retry attempt = %+v, want retry after 2s", a)
+	}
+	if a := store.attempts[dead.ID]; !a.Dead {
+		t.Errorf("last attempt = %+v, want dead", a)
+	}
+}
+
+func TestDispatcher_RefusesPrivateTargets(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	delivery := models.PendingDelivery{URL: server.URL, Secret: "whsec_test"}
+	delivery.ID, delivery.Payload = uuid.New(), json.RawMessage(`{}`)
+	store := &fakeStore{pending: []models.PendingDelivery{delivery}, attempts: map[uuid.UUID]models.DeliveryAttempt{}}
+	dispatcher := NewDispatcher(store, Config{PollInterval: time.Second, BatchSize: 10, Timeout: time.Second, MaxAttempts: 3, MaxBackoff: time.Minute}, logging.Discard())
+
+	if _, err := dispatcher.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() unexpected error: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("loopback endpoint received %d requests, want 0", calls)
+	}
+	if a := store.attempts[delivery.ID]; a.Delivered || !strings.Contains(a.Error, models.ErrWebhookURLNotPublic.Error()) {
+		t.Errorf("attempt = %+v, want refused as not public", a)
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{url: "https://93.184.216.34/hooks"},
+		{url: "http://127.0.0.1:8080/hooks", wantErr: models.ErrWebhookURLNotPublic},
+		{url: "http://[::1]/hooks", wantErr: models.ErrWebhookURLNotPublic},
+		{url: "http://169.254.169.254/latest/meta-data", wantErr: models.ErrWebhookURLNotPublic},
+		{url: "http://10.0.0.5/hooks", wantErr: models.ErrWebhookURLNotPublic},
+		{url: "http://192.168.1.1/hooks", wantErr: models.ErrWebhookURLNotPublic},
+		{url: "http://0.0.0.0/hooks", wantErr: models.ErrWebhookURLNotPublic},
+		{url: "http://[::ffff:172.16.0.1]/hooks", wantErr: models.ErrWebhookURLNotPublic},
+		{url: "http://localhost/hooks", wantErr: models.ErrWebhookURLNotPublic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := CheckTarget(context.Background(), tt.url)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("CheckTarget() unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckTarget() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_wallet_operations_append_only ON wallet_operations;
+DROP FUNCTION IF EXISTS wallet_operations_append_only();
+DROP INDEX IF EXISTS idx_wallet_operations_wallet_seq;
+DROP TABLE IF EXISTS wallet_operations;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS wallet_operations (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+seq BIGSERIAL NOT NULL UNIQUE,
+wallet_id UUID NOT NULL REFERENCES wallets(id),
+operation_type VARCHAR(32) NOT NULL,
+amount INT NOT NULL CHECK (amount > 0),
+balance_before INT NOT NULL,
+balance_after INT NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_operations_wallet_seq ON wallet_operations(wallet_id, seq DESC);
+
+CREATE OR REPLACE FUNCTION wallet_operations_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'wallet_operations is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_wallet_operations_append_only ON wallet_operations;
+CREATE TRIGGER trg_wallet_operations_append_only
+BEFORE UPDATE OR DELETE ON wallet_operations
+FOR EACH ROW EXECUTE FUNCTION wallet_operations_append_only();
+
+INSERT INTO wallet_operations (wallet_id, operation_type, amount, balance_before, balance_after)
+SELECT w.id, 'OPENING_BALANCE', w.balance, 0, w.balance
+FROM wallets w
+WHERE w.balance > 0
+AND NOT EXISTS (SELECT 1 FROM wallet_operations o WHERE o.wallet_id = w.id);
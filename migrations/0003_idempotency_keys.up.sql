@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+key VARCHAR(255) PRIMARY KEY,
+request_hash CHAR(64) NOT NULL,
+response_status INT,
+response_body BYTEA,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+completed_at TIMESTAMPTZ
+);
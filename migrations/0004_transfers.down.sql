@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_wallet_operations_transfer_id;
+ALTER TABLE wallet_operations DROP COLUMN IF EXISTS transfer_id;
@@ -0,0 +1,3 @@
+ALTER TABLE wallet_operations ADD COLUMN IF NOT EXISTS transfer_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_wallet_operations_transfer_id ON wallet_operations(transfer_id) WHERE transfer_id IS NOT NULL;
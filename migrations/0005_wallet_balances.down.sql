@@ -0,0 +1,10 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS balance INT NOT NULL DEFAULT 0 CHECK (balance >= 0);
+
+UPDATE wallets w SET balance = b.balance
+FROM wallet_balances b
+WHERE b.wallet_id = w.id AND b.currency = 'RUB';
+
+DROP INDEX IF EXISTS idx_wallet_operations_wallet_currency_seq;
+ALTER TABLE wallet_operations DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS wallet_balances;
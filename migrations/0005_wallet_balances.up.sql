@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS wallet_balances (
+wallet_id UUID NOT NULL REFERENCES wallets(id),
+currency CHAR(3) NOT NULL,
+balance INT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+PRIMARY KEY (wallet_id, currency)
+);
+
+ALTER TABLE wallet_operations ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE wallet_operations ALTER COLUMN currency DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_wallet_operations_wallet_currency_seq ON wallet_operations(wallet_id, currency, seq DESC);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'wallets' AND column_name = 'balance') THEN
+        INSERT INTO wallet_balances (wallet_id, currency, balance, created_at, updated_at)
+        SELECT id, 'RUB', balance, created_at, updated_at FROM wallets
+        ON CONFLICT (wallet_id, currency) DO NOTHING;
+
+        ALTER TABLE wallets DROP COLUMN balance;
+    END IF;
+END
+$$;
@@ -0,0 +1,6 @@
+ALTER TABLE wallet_operations
+    ALTER COLUMN amount TYPE INT,
+    ALTER COLUMN balance_before TYPE INT,
+    ALTER COLUMN balance_after TYPE INT;
+
+ALTER TABLE wallet_balances ALTER COLUMN balance TYPE INT;
@@ -0,0 +1,6 @@
+ALTER TABLE wallet_balances ALTER COLUMN balance TYPE BIGINT;
+
+ALTER TABLE wallet_operations
+    ALTER COLUMN amount TYPE BIGINT,
+    ALTER COLUMN balance_before TYPE BIGINT,
+    ALTER COLUMN balance_after TYPE BIGINT;
@@ -0,0 +1,5 @@
+ALTER TABLE wallet_operations DROP COLUMN IF EXISTS hold_id;
+
+DROP INDEX IF EXISTS idx_wallet_holds_expires_at;
+DROP INDEX IF EXISTS idx_wallet_holds_active;
+DROP TABLE IF EXISTS wallet_holds;
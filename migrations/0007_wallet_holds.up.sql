@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS wallet_holds (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+wallet_id UUID NOT NULL REFERENCES wallets(id),
+currency CHAR(3) NOT NULL,
+amount BIGINT NOT NULL CHECK (amount > 0),
+captured_amount BIGINT NOT NULL DEFAULT 0 CHECK (captured_amount >= 0 AND captured_amount <= amount),
+status VARCHAR(16) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'CAPTURED', 'RELEASED', 'EXPIRED')),
+expires_at TIMESTAMPTZ NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_holds_active ON wallet_holds(wallet_id, currency) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_wallet_holds_expires_at ON wallet_holds(expires_at) WHERE status = 'ACTIVE';
+
+ALTER TABLE wallet_operations ADD COLUMN IF NOT EXISTS hold_id UUID;
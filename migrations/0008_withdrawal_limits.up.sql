@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS withdrawal_limits (
+id BIGSERIAL PRIMARY KEY,
+wallet_id UUID REFERENCES wallets(id),
+currency CHAR(3) NOT NULL,
+max_single BIGINT CHECK (max_single > 0),
+daily BIGINT CHECK (daily > 0),
+monthly BIGINT CHECK (monthly > 0),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+UNIQUE NULLS NOT DISTINCT (wallet_id, currency)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_wallet_status_changes_wallet_id;
+DROP TABLE IF EXISTS wallet_status_changes;
+ALTER TABLE wallets DROP COLUMN IF EXISTS status;
@@ -0,0 +1,14 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'ACTIVE'
+CHECK (status IN ('ACTIVE', 'FROZEN', 'DEBIT_BLOCKED', 'CLOSED'));
+
+CREATE TABLE IF NOT EXISTS wallet_status_changes (
+id BIGSERIAL PRIMARY KEY,
+wallet_id UUID NOT NULL REFERENCES wallets(id),
+from_status VARCHAR(16) NOT NULL,
+to_status VARCHAR(16) NOT NULL,
+reason TEXT NOT NULL,
+actor VARCHAR(255) NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_status_changes_wallet_id ON wallet_status_changes(wallet_id, id DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_wallet_owners_principal;
+DROP TABLE IF EXISTS wallet_owners;
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+name VARCHAR(255) NOT NULL,
+key_hash CHAR(64) NOT NULL UNIQUE,
+principal VARCHAR(255) NOT NULL,
+role VARCHAR(16) NOT NULL CHECK (role IN ('CLIENT', 'ADMIN')),
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+revoked_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS wallet_owners (
+wallet_id UUID NOT NULL REFERENCES wallets(id),
+principal VARCHAR(255) NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+PRIMARY KEY (wallet_id, principal)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_owners_principal ON wallet_owners(principal);
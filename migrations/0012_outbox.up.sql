@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+id UUID PRIMARY KEY,
+seq BIGSERIAL NOT NULL UNIQUE,
+wallet_id UUID NOT NULL,
+event_type VARCHAR(64) NOT NULL,
+payload JSONB NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+attempts INTEGER NOT NULL DEFAULT 0,
+next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+last_error TEXT,
+published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(wallet_id, seq) WHERE published_at IS NULL;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+id UUID PRIMARY KEY,
+principal VARCHAR(255) NOT NULL,
+all_wallets BOOLEAN NOT NULL DEFAULT FALSE,
+url TEXT NOT NULL,
+event_types TEXT[] NOT NULL DEFAULT '{}',
+secret VARCHAR(128) NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_principal ON webhook_subscriptions(principal);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+id UUID PRIMARY KEY,
+subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+event_id UUID NOT NULL,
+event_type VARCHAR(64) NOT NULL,
+payload JSONB NOT NULL,
+status VARCHAR(16) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'DEAD')),
+attempts INTEGER NOT NULL DEFAULT 0,
+next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+last_status_code INTEGER,
+last_error TEXT,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+delivered_at TIMESTAMPTZ,
+UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at DESC);
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS trg_balance_adjustments_append_only ON balance_adjustments;
+DROP FUNCTION IF EXISTS balance_adjustments_append_only();
+DROP INDEX IF EXISTS idx_balance_adjustments_wallet;
+DROP TABLE IF EXISTS balance_adjustments;
+DROP INDEX IF EXISTS idx_reconciliation_runs_started_at;
+DROP TABLE IF EXISTS reconciliation_runs;
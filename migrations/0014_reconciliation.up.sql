@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+id UUID PRIMARY KEY,
+started_at TIMESTAMPTZ NOT NULL,
+finished_at TIMESTAMPTZ NOT NULL,
+balances_checked INTEGER NOT NULL,
+mismatches INTEGER NOT NULL,
+repaired INTEGER NOT NULL,
+report JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_runs_started_at ON reconciliation_runs(started_at DESC);
+
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+id UUID PRIMARY KEY,
+run_id UUID NOT NULL,
+wallet_id UUID NOT NULL REFERENCES wallets(id),
+currency CHAR(3) NOT NULL,
+balance_before BIGINT NOT NULL,
+balance_after BIGINT NOT NULL,
+actor VARCHAR(255) NOT NULL,
+reason TEXT NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_adjustments_wallet ON balance_adjustments(wallet_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION balance_adjustments_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'balance_adjustments is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_balance_adjustments_append_only ON balance_adjustments;
+CREATE TRIGGER trg_balance_adjustments_append_only
+BEFORE UPDATE OR DELETE ON balance_adjustments
+FOR EACH ROW EXECUTE FUNCTION balance_adjustments_append_only();
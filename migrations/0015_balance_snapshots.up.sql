@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+wallet_id UUID NOT NULL REFERENCES wallets(id),
+currency CHAR(3) NOT NULL,
+seq BIGINT NOT NULL,
+balance BIGINT NOT NULL,
+as_of TIMESTAMPTZ NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+PRIMARY KEY (wallet_id, currency, seq)
+);
@@ -0,0 +1,17 @@
+DO $$
+DECLARE
+    negative BIGINT;
+BEGIN
+    SELECT COUNT(*) INTO negative FROM wallet_balances WHERE balance < 0;
+    IF negative > 0 THEN
+        RAISE EXCEPTION '% wallet balance(s) are negative after admin reversals; bring them back to zero before rolling back 0016_reversals', negative;
+    END IF;
+END
+$$;
+
+ALTER TABLE wallet_balances DROP CONSTRAINT IF EXISTS wallet_balances_balance_check;
+ALTER TABLE wallet_balances DROP COLUMN IF EXISTS overdraft;
+ALTER TABLE wallet_balances ADD CONSTRAINT wallet_balances_balance_check CHECK (balance >= 0);
+
+DROP INDEX IF EXISTS idx_wallet_operations_reversal_of;
+ALTER TABLE wallet_operations DROP COLUMN IF EXISTS reversal_of;
@@ -0,0 +1,8 @@
+ALTER TABLE wallet_operations ADD COLUMN IF NOT EXISTS reversal_of UUID REFERENCES wallet_operations(id);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_operations_reversal_of ON wallet_operations(reversal_of) WHERE reversal_of IS NOT NULL;
+
+ALTER TABLE wallet_balances ADD COLUMN IF NOT EXISTS overdraft BIGINT NOT NULL DEFAULT 0 CHECK (overdraft >= 0);
+
+ALTER TABLE wallet_balances DROP CONSTRAINT IF EXISTS wallet_balances_balance_check;
+ALTER TABLE wallet_balances ADD CONSTRAINT wallet_balances_balance_check CHECK (balance >= -overdraft);
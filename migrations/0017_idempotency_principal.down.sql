@@ -0,0 +1,6 @@
+DELETE FROM idempotency_keys a USING idempotency_keys b
+WHERE a.key = b.key AND (a.created_at, a.principal_id) > (b.created_at, b.principal_id);
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS principal_id;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
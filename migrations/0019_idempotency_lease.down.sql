@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_idempotency_keys_created_at;
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS reserved_at;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS reservation;
@@ -0,0 +1,7 @@
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS reservation UUID;
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS reserved_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE idempotency_keys SET reservation = md5(random()::text || principal_id || key)::uuid, reserved_at = created_at WHERE reservation IS NULL;
+ALTER TABLE idempotency_keys ALTER COLUMN reservation SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_rate_limit_buckets_updated_at;
+
+DELETE FROM rate_limit_buckets WHERE length(key) > 255;
+ALTER TABLE rate_limit_buckets ALTER COLUMN key TYPE VARCHAR(255);
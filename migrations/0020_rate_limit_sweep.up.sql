@@ -0,0 +1,3 @@
+ALTER TABLE rate_limit_buckets ALTER COLUMN key TYPE TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);